@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"blinders/packages/account"
+	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
+	"blinders/packages/transport"
+
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var runner *account.Runner
+
+var AccountCommand = cli.Command{
+	Name: "account",
+	Subcommands: []*cli.Command{
+		&deleteAccountCommand,
+		&exportAccountCommand,
+		&runAccountJobCommand,
+		&serveAccountJobsCommand,
+	},
+	Before: func(ctx *cli.Context) error {
+		initCtx, cancel := context.WithTimeout(ctx.Context, time.Second*5)
+		defer cancel()
+
+		// archives of exports are stored in the private storage, which is read by the rest api
+		privateStorage, err := storage.NewPrivateStorageFromEnv(initCtx)
+		if err != nil {
+			return fmt.Errorf("failed to init private storage: %v", err)
+		}
+
+		r, err := account.NewRunnerFromEnv(initCtx, privateStorage)
+		if err != nil {
+			return fmt.Errorf("failed to init account runner: %v", err)
+		}
+		runner = r
+
+		return nil
+	},
+}
+
+var deleteAccountCommand = cli.Command{
+	Name:        "delete",
+	Description: "delete account of the user, the latest deletion job of the user is resumed if it is not completed",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "user-id",
+			Required: true,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return runAccountJobOfUser(ctx.Context, ctx.String("user-id"), usersdb.AccountDeletionJob)
+	},
+}
+
+var exportAccountCommand = cli.Command{
+	Name:        "export",
+	Description: "export all data of the user into a zip archive, the latest export job of the user is resumed if it is not completed",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "user-id",
+			Required: true,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		return runAccountJobOfUser(ctx.Context, ctx.String("user-id"), usersdb.AccountExportJob)
+	},
+}
+
+var runAccountJobCommand = cli.Command{
+	Name:        "run",
+	Description: "run or resume the account job",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "job-id",
+			Required: true,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		jobID, err := primitive.ObjectIDFromHex(ctx.String("job-id"))
+		if err != nil {
+			return fmt.Errorf("invalid job id: %v", err)
+		}
+
+		return runAccountJob(ctx.Context, jobID)
+	},
+}
+
+var serveAccountJobsCommand = cli.Command{
+	Name: "serve",
+	Description: "run account jobs pushed by the rest api in development, which pushes them to " +
+		"http://localhost:<ACCOUNT_SERVICE_PORT>/ like the account function on lambda",
+	Action: func(ctx *cli.Context) error {
+		port := os.Getenv("ACCOUNT_SERVICE_PORT")
+		if port == "" {
+			return fmt.Errorf("ACCOUNT_SERVICE_PORT is required from environment")
+		}
+
+		http.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+			event := transport.RunAccountJobEvent{}
+			if err := json.NewDecoder(r.Body).Decode(&event); err != nil || event.Type != transport.RunAccountJob {
+				http.Error(w, `{"error": "invalid event"}`, http.StatusBadRequest)
+				return
+			}
+			jobID, err := primitive.ObjectIDFromHex(event.Payload.JobID)
+			if err != nil {
+				http.Error(w, `{"error": "invalid job id"}`, http.StatusBadRequest)
+				return
+			}
+
+			// the job is run after responding, like asynchronous invocations of the account function
+			go func() {
+				if err := runAccountJob(context.Background(), jobID); err != nil {
+					log.Printf("account job %s failed: %v\n", jobID.Hex(), err)
+				}
+			}()
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		fmt.Println("running account jobs on port", port)
+		return http.ListenAndServe(":"+port, nil)
+	},
+}
+
+func runAccountJobOfUser(ctx context.Context, rawUserID string, jobType usersdb.AccountJobType) error {
+	userID, err := primitive.ObjectIDFromHex(rawUserID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %v", err)
+	}
+
+	user, err := runner.UsersDB.UsersRepo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %v", err)
+	}
+	job, err := runner.UsersDB.AccountJobsRepo.GetOrInsertAccountJob(user, jobType)
+	if err != nil {
+		return fmt.Errorf("failed to get %s job: %v", jobType, err)
+	}
+
+	return runAccountJob(ctx, job.ID)
+}
+
+func runAccountJob(ctx context.Context, jobID primitive.ObjectID) error {
+	fmt.Println("running account job:", jobID.Hex())
+	job, err := runner.Run(ctx, jobID)
+	if job != nil {
+		for _, step := range job.Steps {
+			fmt.Printf("%s: %d affected\n", step.Name, step.Affected)
+		}
+		fmt.Println("status:", job.Status)
+		if job.Result != "" {
+			fmt.Println("result:", job.Result)
+		}
+	}
+
+	return err
+}
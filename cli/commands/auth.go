@@ -10,29 +10,28 @@ import (
 	"blinders/packages/utils"
 
 	firebaseAuth "firebase.google.com/go/auth"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/urfave/cli/v2"
 )
 
-var client *firebaseAuth.Client
-
 var AuthCommand = cli.Command{
 	Name:        "auth",
-	Subcommands: []*cli.Command{&loadAuthCommand, &genWSCatCommand},
-	Before: func(ctx *cli.Context) error {
-		env := ctx.String("env")
-		adminJSON, err := utils.GetFile(fmt.Sprintf("firebase.admin.%v.json", env))
-		if err != nil {
-			return err
-		}
-
-		a, err := auth.NewFirebaseManager(adminJSON)
-		if err != nil {
-			return err
-		}
-		client = a.Client
+	Subcommands: []*cli.Command{&loadAuthCommand, &genWSCatCommand, &mintTokenCommand},
+}
 
-		return nil
-	},
+// firebaseClient loads the firebase auth client of the environment
+func firebaseClient(ctx *cli.Context) (*firebaseAuth.Client, error) {
+	env := ctx.String("env")
+	adminJSON, err := utils.GetFile(fmt.Sprintf("firebase.admin.%v.json", env))
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := auth.NewFirebaseManager(adminJSON)
+	if err != nil {
+		return nil, err
+	}
+	return a.Client, nil
 }
 
 var loadAuthCommand = cli.Command{
@@ -58,6 +57,11 @@ var loadAuthCommand = cli.Command{
 			log.Fatal("USER_UID is required from environment")
 		}
 
+		client, err := firebaseClient(ctx)
+		if err != nil {
+			return err
+		}
+
 		cacheFile := fmt.Sprintf("auth.%v.json", env)
 		idToken, authToken, err := authutils.LoadFirebaseAuthForUserWithCache(
 			client,
@@ -104,6 +108,11 @@ var genWSCatCommand = cli.Command{
 
 		endpoint := ctx.String("endpoint")
 
+		client, err := firebaseClient(ctx)
+		if err != nil {
+			return err
+		}
+
 		cacheFile := fmt.Sprintf("auth.%v.json", env)
 		idToken, _, err := authutils.LoadFirebaseAuthForUserWithCache(
 			client,
@@ -120,3 +129,64 @@ var genWSCatCommand = cli.Command{
 		return nil
 	},
 }
+
+var mintTokenCommand = cli.Command{
+	Name: "mint-token",
+	Description: "mint a jwt of any uid with the local key (LOCAL_JWT_SECRET or LOCAL_JWT_PRIVATE_KEY_FILE), " +
+		"which is verified by services running with AUTH_PROVIDER=local",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "uid",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name: "email",
+		},
+		&cli.StringFlag{
+			Name: "name",
+		},
+		&cli.StringSliceFlag{
+			Name:  "role",
+			Usage: "custom role claim, could be repeated",
+		},
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Value: auth.DefaultLocalJWTTTL,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		m, err := auth.NewLocalJWTManagerFromEnv()
+		if err != nil {
+			return err
+		}
+
+		uid := ctx.String("uid")
+		email, name := ctx.String("email"), ctx.String("name")
+		if email == "" {
+			email = fmt.Sprintf("%s@blinders.local", uid)
+		}
+		if name == "" {
+			name = uid
+		}
+		for _, role := range ctx.StringSlice("role") {
+			if _, err := auth.ParseRole(role); err != nil {
+				return err
+			}
+		}
+
+		m.TTL = ctx.Duration("ttl")
+		token, err := m.Sign(auth.LocalClaims{
+			Email:            email,
+			Name:             name,
+			Roles:            ctx.StringSlice("role"),
+			RegisteredClaims: jwt.RegisteredClaims{Subject: uid},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mint token: %v", err)
+		}
+
+		fmt.Printf("JWT of %v: %v\n", email, token)
+
+		return nil
+	},
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"fmt"
+
+	"blinders/packages/auth"
+	"blinders/packages/db/usersdb"
+	dbutils "blinders/packages/db/utils"
+
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var usersDB *usersdb.UsersDB
+
+var UsersCommand = cli.Command{
+	Name:        "users",
+	Subcommands: []*cli.Command{&backfillSearchCommand, &grantRoleCommand, &revokeRoleCommand},
+	Before: func(_ *cli.Context) error {
+		db, err := dbutils.InitMongoDatabaseFromEnv("USERS")
+		if err != nil {
+			return fmt.Errorf("failed to init users db: %v", err)
+		}
+		usersDB = usersdb.NewUsersDB(db)
+
+		return nil
+	},
+}
+
+var backfillSearchCommand = cli.Command{
+	Name:        "backfill-search",
+	Description: "set lowercase search fields of users which are created before user search is introduced",
+	Action: func(_ *cli.Context) error {
+		modified, err := usersDB.UsersRepo.BackfillSearchFields()
+		if err != nil {
+			return fmt.Errorf("failed to backfill search fields: %v", err)
+		}
+
+		fmt.Println("backfilled users:", modified)
+		return nil
+	},
+}
+
+var roleFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "user-id",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:     "role",
+		Required: true,
+		Usage:    "one of: moderator, admin",
+	},
+}
+
+var grantRoleCommand = cli.Command{
+	Name:        "grant-role",
+	Description: "grant the role to the user, it takes effect on the next request of the user",
+	Flags:       roleFlags,
+	Action: func(ctx *cli.Context) error {
+		userID, role, err := parseRoleFlags(ctx)
+		if err != nil {
+			return err
+		}
+		if err := usersDB.UsersRepo.AddRole(userID, string(role)); err != nil {
+			return fmt.Errorf("failed to grant role: %v", err)
+		}
+
+		fmt.Printf("granted role %s to user %s\n", role, userID.Hex())
+		return nil
+	},
+}
+
+var revokeRoleCommand = cli.Command{
+	Name:        "revoke-role",
+	Description: "revoke the role of the user",
+	Flags:       roleFlags,
+	Action: func(ctx *cli.Context) error {
+		userID, role, err := parseRoleFlags(ctx)
+		if err != nil {
+			return err
+		}
+		if err := usersDB.UsersRepo.RemoveRole(userID, string(role)); err != nil {
+			return fmt.Errorf("failed to revoke role: %v", err)
+		}
+
+		fmt.Printf("revoked role %s of user %s\n", role, userID.Hex())
+		return nil
+	},
+}
+
+func parseRoleFlags(ctx *cli.Context) (primitive.ObjectID, auth.Role, error) {
+	userID, err := primitive.ObjectIDFromHex(ctx.String("user-id"))
+	if err != nil {
+		return primitive.NilObjectID, "", fmt.Errorf("invalid user id: %v", err)
+	}
+	role, err := auth.ParseRole(ctx.String("role"))
+	if err != nil {
+		return primitive.NilObjectID, "", err
+	}
+
+	return userID, role, nil
+}
@@ -4,6 +4,7 @@ go 1.22.0
 
 require (
 	firebase.google.com/go v3.13.0+incompatible
+	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/joho/godotenv v1.5.1
 	github.com/urfave/cli/v2 v2.27.1
 )
@@ -0,0 +1,7 @@
+module blinders/functions/account
+
+go 1.22.0
+
+require github.com/aws/aws-lambda-go v1.46.0
+
+require github.com/stretchr/testify v1.8.4 // indirect
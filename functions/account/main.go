@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"blinders/packages/account"
+	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
+	"blinders/packages/transport"
+	"blinders/packages/utils"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var runner *account.Runner
+
+func init() {
+	env := os.Getenv("ENVIRONMENT")
+	log.Println("account function running on environment:", env)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// archives of export jobs and feedback screenshots are stored in the PRIVATE_BUCKET bucket
+	privateStorage, err := storage.NewPrivateStorageFromEnv(ctx)
+	if err != nil {
+		log.Fatal("failed to init private storage:", err)
+	}
+	runner, err = account.NewRunnerFromEnv(ctx, privateStorage)
+	if err != nil {
+		log.Fatal("failed to init account runner:", err)
+	}
+}
+
+// LambdaHandler runs the account job of the event, the job is resumed from its last completed step
+// if it has been run before
+func LambdaHandler(ctx context.Context, req any) error {
+	event, err := utils.JSONConvert[transport.RunAccountJobEvent](req)
+	if err != nil {
+		log.Println("can not parse event:", err)
+		return fmt.Errorf("can not parse event")
+	}
+	if event.Type != transport.RunAccountJob {
+		return fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+
+	jobID, err := primitive.ObjectIDFromHex(event.Payload.JobID)
+	if err != nil {
+		return fmt.Errorf("invalid job id: %s", event.Payload.JobID)
+	}
+
+	job, err := runner.Run(ctx, jobID)
+	if err == usersdb.ErrAccountJobNotClaimable {
+		// the job is completed or being run by another invocation, do not let lambda retry the event
+		log.Printf("account job %s is not claimable\n", jobID.Hex())
+		return nil
+	} else if err != nil {
+		return err
+	}
+	log.Printf("account job %s finished with status %s\n", job.ID.Hex(), job.Status)
+
+	return nil
+}
+
+func main() {
+	lambda.Start(LambdaHandler)
+}
@@ -73,8 +73,7 @@ func init() {
 	core := explore.NewExplorer(matchingRepo, usersRepo, redisClient)
 	service := exploreapi.NewService(core, redisClient, transporter)
 
-	adminJSON, _ := utils.GetFile("firebase.admin.json")
-	auth, err := auth.NewFirebaseManager(adminJSON)
+	auth, err := auth.NewManagerFromEnv("firebase.admin.json")
 	if err != nil {
 		panic(err)
 	}
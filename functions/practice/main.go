@@ -6,9 +6,12 @@ import (
 	"os"
 
 	"blinders/packages/auth"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/matchingdb"
 	"blinders/packages/db/practicedb"
 	"blinders/packages/db/usersdb"
 	dbutils "blinders/packages/db/utils"
+	"blinders/packages/suggest"
 	"blinders/packages/transport"
 	"blinders/packages/utils"
 	practiceapi "blinders/services/practice/api"
@@ -20,6 +23,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/sashabaranov/go-openai"
 )
 
 var fiberLambda *fiberadapter.FiberLambda
@@ -32,18 +36,25 @@ func init() {
 		log.Fatal(err)
 	}
 	usersRepo := usersdb.NewUsersRepo(usersDB)
+	matchingDB, err := dbutils.InitMongoDatabaseFromEnv("MATCHING")
+	if err != nil {
+		log.Fatal(err)
+	}
+	matchingRepo := matchingdb.NewMatchingRepo(matchingDB)
 	practiceDB, err := dbutils.InitMongoDatabaseFromEnv("PRACTICE")
 	if err != nil {
 		log.Fatal(err)
 	}
 	flashcardsRepo := practicedb.NewFlashcardsRepo(practiceDB)
 	snapshotRepo := practicedb.NewSnapshotsRepo(practiceDB)
-
-	adminConfig, err := utils.GetFile("firebase.admin.json")
+	chatDB, err := dbutils.InitMongoDatabaseFromEnv("CHAT")
 	if err != nil {
 		log.Fatal(err)
 	}
-	auth, err := auth.NewFirebaseManager(adminConfig)
+	messagesRepo := chatdb.NewMessagesRepo(chatDB)
+	conversationsRepo := chatdb.NewConversationsRepo(chatDB)
+
+	auth, err := auth.NewManagerFromEnv("firebase.admin.json")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -58,13 +69,26 @@ func init() {
 	}
 	transport := transport.NewLambdaTransportWithConsumers(cfg, transportConsumers)
 
+	// recap is disabled if openai api key is not provided
+	var suggester suggest.Suggester
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		suggester, _ = suggest.NewGPTSuggester(
+			openai.NewClient(apiKey),
+			suggest.WithMaxTokens(suggest.RecapMaxTokens),
+		)
+	}
+
 	app := fiber.New(fiber.Config{})
 	api := practiceapi.NewService(
 		app,
 		auth,
 		usersRepo,
+		matchingRepo,
 		flashcardsRepo,
 		snapshotRepo,
+		messagesRepo,
+		conversationsRepo,
+		suggester,
 		transport,
 	)
 	api.App.Use(logger.New(logger.Config{Format: utils.DefaultGinLoggerFormat}))
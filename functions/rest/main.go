@@ -4,13 +4,13 @@ import (
 	"context"
 	"log"
 	"os"
-	"sync"
 
 	"blinders/packages/auth"
 	"blinders/packages/db/chatdb"
 	"blinders/packages/db/matchingdb"
 	"blinders/packages/db/usersdb"
 	dbutils "blinders/packages/db/utils"
+	"blinders/packages/storage"
 	"blinders/packages/transport"
 	"blinders/packages/utils"
 	restapi "blinders/services/rest/api"
@@ -22,50 +22,22 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var (
-	fiberLambda *fiberadapter.FiberLambda
-	err         error
-)
+var fiberLambda *fiberadapter.FiberLambda
 
 func init() {
 	log.Println("rest api running on environment:", os.Getenv("ENVIRONMENT"))
 
-	var usersDB *mongo.Database
-	var chatDB *mongo.Database
-	var matchingDB *mongo.Database
-	wg := sync.WaitGroup{}
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		usersDB, err = dbutils.InitMongoDatabaseFromEnv("USERS")
-		if err != nil {
-			log.Fatal("failed to init users db:", err)
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		chatDB, err = dbutils.InitMongoDatabaseFromEnv("CHAT")
-		if err != nil {
-			log.Fatal("failed to init chat db:", err)
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		matchingDB, err = dbutils.InitMongoDatabaseFromEnv("MATCHING")
-		if err != nil {
-			log.Fatal("failed to init matching db:", err)
-		}
-	}()
-	wg.Wait()
-
-	adminConfig, err := utils.GetFile("firebase.admin.json")
+	// databases on the same url share one client, so that friend requests could be accepted
+	// in the same transaction with conversation creation
+	dbs, err := dbutils.InitMongoDatabasesFromEnv("USERS", "CHAT", "MATCHING")
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal("failed to init databases:", err)
 	}
-	authManager, err := auth.NewFirebaseManager(adminConfig)
+	usersDB, chatDB, matchingDB := dbs[0], dbs[1], dbs[2]
+
+	authManager, err := auth.NewManagerFromEnv("firebase.admin.json")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -75,17 +47,29 @@ func init() {
 		log.Fatal("failed to load aws config:", err)
 	}
 
-	app := fiber.New()
+	// archives of exports and feedback screenshots are stored in the PRIVATE_BUCKET bucket,
+	// they are not available without it
+	privateStorage, err := storage.NewPrivateStorageFromEnv(context.Background())
+	if err != nil {
+		log.Println("failed to init private storage:", err)
+		privateStorage = nil
+	}
+
+	app := fiber.New(fiber.Config{BodyLimit: restapi.MaxRequestBodySize})
 	api := restapi.NewManager(
 		app, authManager,
 		usersdb.NewUsersDB(usersDB),
 		chatdb.NewChatDB(chatDB),
 		matchingdb.NewMatchingRepo(matchingDB),
+		nil, // avatars are not stored on lambda disks, upload is disabled until a cloud storage is configured
+		privateStorage,
 		transport.NewLambdaTransport(cfg),
 		transport.ConsumerMap{
 			transport.Notification: os.Getenv("NOTIFICATION_FUNCTION_NAME"),
 			transport.Explore:      os.Getenv("EXPLORE_FUNCTION_NAME"),
+			transport.Account:      os.Getenv("ACCOUNT_FUNCTION_NAME"),
 		},
+		auth.NewTicketStoreFromEnv(),
 	)
 
 	api.App.Use(logger.New(logger.Config{Format: utils.DefaultGinLoggerFormat}))
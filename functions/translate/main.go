@@ -15,7 +15,6 @@ import (
 	dbutils "blinders/packages/db/utils"
 	"blinders/packages/translate"
 	"blinders/packages/transport"
-	"blinders/packages/utils"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -58,18 +57,14 @@ func init() {
 		log.Fatal("failed to init users db:", err)
 	}
 
-	adminConfig, err := utils.GetFile("firebase.admin.json")
-	if err != nil {
-		log.Fatal(err)
-	}
-	authManager, err := auth.NewFirebaseManager(adminConfig)
+	authManager, err := auth.NewManagerFromEnv("firebase.admin.json")
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	authMiddleware = auth.LambdaAuthMiddleware(
 		authManager,
-		usersdb.NewUsersRepo(usersDB),
+		auth.NewCachedUsers(usersdb.NewUsersRepo(usersDB), auth.NewUserCacheFromEnv()),
 	)
 }
 
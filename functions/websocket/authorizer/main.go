@@ -2,13 +2,11 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 
 	"blinders/packages/auth"
 	"blinders/packages/db/usersdb"
 	dbutils "blinders/packages/db/utils"
-	"blinders/packages/utils"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -20,8 +18,8 @@ type APIGatewayWebsocketProxyRequest struct {
 }
 
 var (
-	authManager auth.Manager
-	userRepo    *usersdb.UsersRepo
+	authenticator *auth.Authenticator
+	tickets       *auth.TicketStore
 )
 
 func init() {
@@ -29,55 +27,29 @@ func init() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	userRepo = usersdb.NewUsersRepo(usersDB)
+	userRepo := usersdb.NewUsersRepo(usersDB)
 
-	adminConfig, err := utils.GetFile("firebase.admin.json")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	authManager, err = auth.NewFirebaseManager(adminConfig)
+	authManager, err := auth.NewManagerFromEnv("firebase.admin.json")
 	if err != nil {
 		log.Fatal(err)
 	}
+	authenticator = auth.NewAuthenticator(authManager, auth.NewCachedUsers(userRepo, auth.NewUserCacheFromEnv()))
+	tickets = auth.NewTicketStoreFromEnv()
 }
 
 func HandleRequest(
-	_ context.Context,
+	ctx context.Context,
 	request APIGatewayWebsocketProxyRequest,
 ) (events.APIGatewayCustomAuthorizerResponse, error) {
-	jwt := request.QueryStringParameters["token"]
-	authUser, err := authManager.Verify(jwt)
-	if err != nil {
-		return events.APIGatewayCustomAuthorizerResponse{}, err
+	// tickets are issued by the rest api, the token of the query string is kept for clients
+	// which have not moved to tickets yet
+	if ticket := auth.WebsocketTicket(request.QueryStringParameters, request.Headers); ticket != "" {
+		if tickets == nil {
+			return events.APIGatewayCustomAuthorizerResponse{}, auth.ErrWebsocketUnauthorized
+		}
+		return tickets.AuthorizeWebsocket(ctx, ticket, request.MethodArn)
 	}
-
-	user, err := userRepo.GetUserByFirebaseUID(authUser.AuthID)
-	if err != nil {
-		return events.APIGatewayCustomAuthorizerResponse{}, err
-	}
-
-	// Is it secure to log the id out to cloudwatch?
-	// how to log the request tracking efficient and secure
-	log.Println("[authorizer] issued user's policy of", user.ID.Hex())
-
-	userBytes, _ := json.Marshal(authUser)
-	return events.APIGatewayCustomAuthorizerResponse{
-		PrincipalID: user.ID.Hex(),
-		PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
-			Version: "2012-10-17",
-			Statement: []events.IAMPolicyStatement{
-				{
-					Action:   []string{"execute-api:Invoke"},
-					Effect:   "Allow",
-					Resource: []string{request.MethodArn},
-				},
-			},
-		},
-		Context: map[string]interface{}{
-			"user": string(userBytes),
-		},
-	}, nil
+	return authenticator.AuthorizeWebsocket(ctx, request.QueryStringParameters["token"], request.MethodArn)
 }
 
 func main() {
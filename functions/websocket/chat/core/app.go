@@ -2,7 +2,11 @@ package wschat
 
 import (
 	"blinders/packages/db/chatdb"
+	"blinders/packages/db/matchingdb"
+	"blinders/packages/db/usersdb"
 	"blinders/packages/session"
+	"blinders/packages/translate"
+	"blinders/packages/transport"
 )
 
 var app *App
@@ -10,6 +14,15 @@ var app *App
 type App struct {
 	Session *session.Manager
 	ChatDB  *chatdb.ChatDB
+
+	// optional dependencies, slash commands which require a missing dependency
+	// will respond with an error system message
+	UsersRepo    *usersdb.UsersRepo
+	MatchingRepo *matchingdb.MatchingRepo
+	Translator   translate.Translator
+	Explainer    Explainer
+	Transporter  transport.Transport
+	PartnerBot   *PartnerBot
 }
 
 // init app construct an app instance for internal use
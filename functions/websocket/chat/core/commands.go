@@ -0,0 +1,198 @@
+package wschat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/collectingdb"
+	"blinders/packages/translate"
+	"blinders/packages/transport"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Explainer explains a phrase in the context of a sentence,
+// it is implemented by the suggest service
+type Explainer interface {
+	Explain(phrase string, sentence string) (*collectingdb.ExplainResponse, error)
+}
+
+type TranslateCommandResult struct {
+	Text       string              `json:"text"`
+	Translated string              `json:"translated"`
+	Languages  translate.Languages `json:"languages"`
+}
+
+// HandleCommand executes the command and responds with a system message to
+// the connection that sent it. If the command replies to a message, the
+// replied message is used as the context of the command.
+func HandleCommand(
+	userID primitive.ObjectID,
+	connectionID string,
+	conversationID primitive.ObjectID,
+	repliedMessage *chatdb.Message,
+	cmd chatdb.Command,
+	resolveID string,
+) <-chan *DistributeEvent {
+	dCh := make(chan *DistributeEvent)
+
+	go func() {
+		payload := ServerSendSystemMessagePayload{
+			ChatEvent:      ChatEvent{Type: ServerSendSystemMessage},
+			ResolveID:      resolveID,
+			ConversationID: conversationID.Hex(),
+			Command:        cmd.Type,
+		}
+
+		content, data, err := executeCommand(userID, cmd, repliedMessage)
+		if err != nil {
+			payload.Error = AckError{Error: err.Error()}
+		} else {
+			payload.Content = content
+			payload.Data = data
+		}
+
+		dCh <- &DistributeEvent{ConnectionID: connectionID, Payload: payload}
+		dCh <- nil
+	}()
+
+	return dCh
+}
+
+func executeCommand(
+	userID primitive.ObjectID,
+	cmd chatdb.Command,
+	repliedMessage *chatdb.Message,
+) (string, any, error) {
+	switch cmd.Type {
+	case chatdb.TranslateCommand:
+		return executeTranslateCommand(userID, cmd.Args, repliedMessage)
+	case chatdb.ExplainCommand:
+		phrase, sentence, hasSentence := parseExplainArgs(cmd.Args)
+		if repliedMessage != nil && !hasSentence {
+			sentence = repliedMessage.Content
+			if phrase == "" {
+				phrase = sentence
+			}
+		}
+		if phrase == "" {
+			return "", nil, fmt.Errorf(
+				"usage: /explain <phrase> | <sentence>, reply to a message to explain the phrase in it")
+		}
+		return executeExplainCommand(userID, phrase, sentence)
+	case chatdb.DefineCommand:
+		word, sentence, _ := parseExplainArgs(cmd.Args)
+		if word == "" {
+			return "", nil, fmt.Errorf("usage: /define <word> | <sentence>")
+		}
+		return executeExplainCommand(userID, word, sentence)
+	default:
+		return "", nil, fmt.Errorf("command %s is not supported", cmd.Type)
+	}
+}
+
+// parseExplainArgs parses "<phrase> | <sentence>" args to explain the phrase in the sentence,
+// the phrase is used as the sentence too if there is no sentence
+func parseExplainArgs(args string) (phrase string, sentence string, hasSentence bool) {
+	phrase, sentence, _ = strings.Cut(args, "|")
+	phrase, sentence = strings.TrimSpace(phrase), strings.TrimSpace(sentence)
+	if sentence == "" {
+		return phrase, phrase, false
+	}
+	return phrase, sentence, true
+}
+
+func executeTranslateCommand(
+	userID primitive.ObjectID,
+	args string,
+	repliedMessage *chatdb.Message,
+) (string, any, error) {
+	if app.Translator == nil {
+		return "", nil, fmt.Errorf("translate command is not available")
+	}
+
+	langs, text := translate.EnVi, args
+	first, rest, _ := strings.Cut(args, " ")
+	if l := translate.Languages(first); l == translate.EnVi || l == translate.ViEn {
+		langs, text = l, strings.TrimSpace(rest)
+	}
+	if text == "" && repliedMessage != nil {
+		text = repliedMessage.Content
+	}
+	if text == "" {
+		return "", nil, fmt.Errorf("usage: /translate [en-vi|vi-en] <text>")
+	}
+
+	translated, err := app.Translator.Translate(text, langs)
+	if err != nil {
+		log.Println("can not translate text:", err)
+		return "", nil, fmt.Errorf("cannot translate \"%s\"", text)
+	}
+
+	pushCollectingEvent(transport.AddTranslateLogEvent{
+		Event: transport.Event{Type: transport.AddTranslateLog},
+		Payload: collectingdb.TranslateLog{
+			UserID:   userID,
+			Request:  collectingdb.TranslateRequest{Text: text},
+			Response: collectingdb.TranslateResponse{Translate: translated},
+		},
+	})
+
+	return translated, TranslateCommandResult{
+		Text:       text,
+		Translated: translated,
+		Languages:  langs,
+	}, nil
+}
+
+func executeExplainCommand(
+	userID primitive.ObjectID,
+	phrase string,
+	sentence string,
+) (string, any, error) {
+	if app.Explainer == nil {
+		return "", nil, fmt.Errorf("explain command is not available")
+	}
+
+	explanation, err := app.Explainer.Explain(phrase, sentence)
+	if err != nil {
+		log.Println("can not explain phrase:", err)
+		return "", nil, fmt.Errorf("cannot explain \"%s\"", phrase)
+	}
+
+	pushCollectingEvent(transport.AddExplainLogEvent{
+		Event: transport.Event{Type: transport.AddExplainLog},
+		Payload: collectingdb.ExplainLog{
+			UserID:   userID,
+			Request:  collectingdb.ExplainRequest{Text: phrase, Sentence: sentence},
+			Response: *explanation,
+		},
+	})
+
+	return explanation.Translate, explanation, nil
+}
+
+// pushCollectingEvent logs command usage to collecting service, the same way
+// translate and explain apis do. Failures are only logged.
+func pushCollectingEvent(event any) {
+	if app.Transporter == nil {
+		return
+	}
+
+	payload, _ := json.Marshal(event)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err := app.Transporter.Push(
+		ctx,
+		app.Transporter.ConsumerID(transport.CollectingPush),
+		payload,
+	)
+	if err != nil {
+		log.Println("cannot push event to collecting service:", err)
+	}
+}
@@ -0,0 +1,24 @@
+package wschat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExplainArgs(t *testing.T) {
+	phrase, sentence, hasSentence := parseExplainArgs(" break the ice |  He told a joke to break the ice. ")
+	assert.Equal(t, "break the ice", phrase)
+	assert.Equal(t, "He told a joke to break the ice.", sentence)
+	assert.True(t, hasSentence)
+
+	phrase, sentence, hasSentence = parseExplainArgs(" serendipity ")
+	assert.Equal(t, "serendipity", phrase)
+	assert.Equal(t, "serendipity", sentence)
+	assert.False(t, hasSentence)
+
+	phrase, sentence, hasSentence = parseExplainArgs("serendipity |")
+	assert.Equal(t, "serendipity", phrase)
+	assert.Equal(t, "serendipity", sentence)
+	assert.False(t, hasSentence)
+}
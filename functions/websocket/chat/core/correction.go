@@ -0,0 +1,69 @@
+package wschat
+
+import (
+	"fmt"
+	"strings"
+
+	"blinders/packages/db/chatdb"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HandleSendCorrection sends a correction message which corrects a message of another member.
+// The correction is distributed the same way as normal messages.
+func HandleSendCorrection(
+	rawUserID string,
+	connectionID string,
+	payload UserSendCorrectionPayload,
+) (<-chan *DistributeEvent, error) {
+	dCh := make(chan *DistributeEvent)
+
+	userID, _ := primitive.ObjectIDFromHex(rawUserID)
+	conversationID, err := primitive.ObjectIDFromHex(payload.ConversationID)
+	if err != nil {
+		return dCh, fmt.Errorf("invalid conversationId: %s", payload.ConversationID)
+	}
+
+	replyTo, err := primitive.ObjectIDFromHex(payload.ReplyTo)
+	if err != nil {
+		return dCh, fmt.Errorf("invalid replyTo: %s", payload.ReplyTo)
+	}
+
+	corrected := strings.TrimSpace(payload.Content)
+	if corrected == "" {
+		return dCh, fmt.Errorf("corrected content is required")
+	}
+
+	conversation, err := queryConversationOfUser(conversationID, userID)
+	if err != nil {
+		return dCh, fmt.Errorf("failed to query conversation: %v", err)
+	}
+	if conversation.ReadOnly {
+		return dCh, fmt.Errorf("conversation %s is read only", conversationID.Hex())
+	}
+
+	original, err := app.ChatDB.MessagesRepo.GetMessageByID(replyTo)
+	if err != nil || original.ConversationID != conversationID {
+		return dCh, fmt.Errorf("cannot correct message %s", payload.ReplyTo)
+	}
+	if original.SenderID == userID {
+		return dCh, fmt.Errorf("cannot correct your own message")
+	}
+	if original.Type == chatdb.CorrectionMessage {
+		return dCh, fmt.Errorf("cannot correct a correction message")
+	}
+	if original.Content == corrected {
+		return dCh, fmt.Errorf("corrected content is the same as the original")
+	}
+
+	message := constructReplyMessage(userID, conversationID, &original, corrected)
+	message.Type = chatdb.CorrectionMessage
+	message.Correction = &chatdb.MessageCorrection{
+		CorrectedUserID: original.SenderID,
+		Original:        original.Content,
+		Corrected:       corrected,
+		Diff:            chatdb.ComputeCorrectionDiff(original.Content, corrected),
+	}
+
+	return distributeNewMessage(message, *conversation, connectionID, payload.ResolveID), nil
+}
@@ -0,0 +1,118 @@
+package wschat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"blinders/packages/db/chatdb"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Publisher publishes a distributed payload to a websocket connection
+type Publisher func(ctx context.Context, connectionID string, payload any) error
+
+// Dispatcher sends due scheduled messages
+type Dispatcher interface {
+	// Dispatch sends all due scheduled messages, it returns the number of sent messages
+	Dispatch(ctx context.Context) (int, error)
+}
+
+// ScheduledMessageDispatcher dispatches due messages once per call, it is triggered by a scheduler in production
+type ScheduledMessageDispatcher struct {
+	Publish Publisher
+}
+
+func NewScheduledMessageDispatcher(publish Publisher) *ScheduledMessageDispatcher {
+	return &ScheduledMessageDispatcher{Publish: publish}
+}
+
+func (d ScheduledMessageDispatcher) Dispatch(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		scheduled, err := app.ChatDB.ScheduledMessagesRepo.ClaimDueScheduledMessage(time.Now())
+		if err == mongo.ErrNoDocuments {
+			return sent, nil
+		} else if err != nil {
+			log.Println("can not claim due scheduled message:", err)
+			return sent, err
+		}
+
+		if err := d.dispatchMessage(ctx, *scheduled); err != nil {
+			log.Println("can not dispatch scheduled message", scheduled.ID.Hex(), err)
+			_ = app.ChatDB.ScheduledMessagesRepo.MarkScheduledMessageFailed(scheduled.ID, err.Error())
+			continue
+		}
+		sent++
+	}
+
+	return sent, ctx.Err()
+}
+
+// dispatchMessage sends the scheduled message through the same path as HandleSendMessage,
+// so the sender membership is validated again at sending time
+func (d ScheduledMessageDispatcher) dispatchMessage(ctx context.Context, scheduled chatdb.ScheduledMessage) error {
+	payload := UserSendMessagePayload{
+		ChatEvent:      ChatEvent{Type: UserSendMessage},
+		Content:        scheduled.Content,
+		ConversationID: scheduled.ConversationID.Hex(),
+	}
+	if scheduled.ReplyTo != nil {
+		payload.ReplyTo = scheduled.ReplyTo.Hex()
+	}
+	if _, ok := chatdb.ParseCommand(payload.Content); ok {
+		// commands are private responses to a connection, they can not be scheduled
+		return fmt.Errorf("slash commands can not be scheduled")
+	}
+
+	conversation, repliedMessage, err := validateSendMessagePayload(scheduled.SenderID, payload)
+	if err != nil {
+		return err
+	}
+	message := constructMessageOfUser(scheduled.SenderID, *conversation, repliedMessage, payload.Content)
+
+	dCh := distributeNewMessage(message, *conversation, "", "")
+	for {
+		e := <-dCh
+		if e == nil {
+			break
+		}
+		if err := d.Publish(ctx, e.ConnectionID, e.Payload); err != nil {
+			log.Println("can not publish scheduled message:", err)
+		}
+	}
+
+	return app.ChatDB.ScheduledMessagesRepo.MarkScheduledMessageSent(scheduled.ID, message.ID)
+}
+
+// TickerDispatcher dispatches due messages periodically in process, it is used for local runs
+type TickerDispatcher struct {
+	Dispatcher Dispatcher
+	Interval   time.Duration
+}
+
+func NewTickerDispatcher(dispatcher Dispatcher, interval time.Duration) *TickerDispatcher {
+	return &TickerDispatcher{Dispatcher: dispatcher, Interval: interval}
+}
+
+// Run dispatches due messages at every tick until the context is done
+func (d TickerDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := d.Dispatcher.Dispatch(ctx)
+			if err != nil {
+				log.Println("failed to dispatch scheduled messages:", err)
+			} else if sent != 0 {
+				log.Printf("dispatched %d scheduled messages\n", sent)
+			}
+		}
+	}
+}
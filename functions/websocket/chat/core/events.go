@@ -7,10 +7,16 @@ type ChatEventType string
 const (
 	UserPing                  ChatEventType = "USER:PING"
 	UserSendMessage           ChatEventType = "USER:SEND_MESSAGE"
+	UserSendCorrection        ChatEventType = "USER:SEND_CORRECTION"
 	UserUpdateMessageStatus   ChatEventType = "USER:UPDATE_MESSAGE_STATUS"
+	UserPinMessage            ChatEventType = "USER:PIN_MESSAGE"
+	UserUnpinMessage          ChatEventType = "USER:UNPIN_MESSAGE"
 	ServerSendMessage         ChatEventType = "SERVER:SEND_MESSAGE"
+	ServerSendMentionMessage  ChatEventType = "SERVER:SEND_MENTION_MESSAGE"
 	ServerAckSendMessage      ChatEventType = "SERVER:ACK_SEND_MESSAGE"
 	ServerUpdateMessageStatus ChatEventType = "SERVER:UPDATE_MESSAGE_STATUS"
+	ServerSendSystemMessage   ChatEventType = "SERVER:SEND_SYSTEM_MESSAGE"
+	ServerUpdatePins          ChatEventType = "SERVER:UPDATE_PINS"
 )
 
 type ChatEvent struct {
@@ -29,6 +35,16 @@ type UserSendMessagePayload struct {
 	ResolveID      string `json:"resolveId"` // it helps client side resolve the message
 }
 
+// UserSendCorrectionPayload corrects a message of another member,
+// the corrected message is required in ReplyTo and the corrected text is put in Content
+type UserSendCorrectionPayload struct {
+	ChatEvent      `json:",inline"`
+	Content        string `json:"content"`
+	ConversationID string `json:"conversationId"`
+	ReplyTo        string `json:"replyTo"`
+	ResolveID      string `json:"resolveId"`
+}
+
 type ServerAckSendMessagePayload struct {
 	ChatEvent `json:",inline"`
 	ResolveID string         `json:"resolveId"` // send ack response to sender
@@ -36,9 +52,44 @@ type ServerAckSendMessagePayload struct {
 	Error     AckError       `json:"error,omitempty"`
 }
 
+// ServerSendMessagePayload is sent with type ServerSendMentionMessage
+// to the recipients mentioned in the message, so they could highlight the message.
+// Notify tells the recipient to show a notification of the message, it is false for members
+// who muted the conversation and for mentioned members, who are notified by the mention event.
 type ServerSendMessagePayload struct {
 	ChatEvent `json:",inline"`
 	Message   chatdb.Message `json:"message"`
+	Notify    bool           `json:"notify"`
+}
+
+// ServerSendSystemMessagePayload is a private message, it is only sent to the
+// connection which triggered it and never stored as a conversation message
+type ServerSendSystemMessagePayload struct {
+	ChatEvent      `json:",inline"`
+	ResolveID      string             `json:"resolveId"`
+	ConversationID string             `json:"conversationId"`
+	Command        chatdb.CommandType `json:"command"`
+	Content        string             `json:"content"`
+	Data           any                `json:"data,omitempty"`
+	Error          AckError           `json:"error,omitempty"`
+}
+
+// UserPinMessagePayload is used for both pin and unpin events
+type UserPinMessagePayload struct {
+	ChatEvent      `json:",inline"`
+	ConversationID string `json:"conversationId"`
+	MessageID      string `json:"messageId"`
+	ResolveID      string `json:"resolveId"`
+}
+
+type ServerUpdatePinsPayload struct {
+	ChatEvent      `json:",inline"`
+	ResolveID      string                 `json:"resolveId,omitempty"` // only sent to the connection which updated pins
+	ConversationID string                 `json:"conversationId"`
+	Pinned         bool                   `json:"pinned"` // the message is pinned or unpinned
+	MessageID      string                 `json:"messageId"`
+	UpdatedBy      string                 `json:"updatedBy"`
+	Pins           []chatdb.PinnedMessage `json:"pins"`
 }
 
 type MessageStatus string
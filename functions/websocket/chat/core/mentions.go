@@ -0,0 +1,128 @@
+package wschat
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"blinders/packages/db/chatdb"
+	"blinders/packages/transport"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ParseMentions resolves "@name" mentions in the content against the names of members,
+// a member could have many names (nickname, user name). Names are matched case-insensitively,
+// the longest name is preferred, e.g. "@Anna Lee" mentions "Anna Lee" instead of "Anna".
+func ParseMentions(content string, names map[primitive.ObjectID][]string) []primitive.ObjectID {
+	mentions := make([]primitive.ObjectID, 0)
+	lowerContent := strings.ToLower(content)
+
+	for i := 0; i < len(lowerContent); i++ {
+		if lowerContent[i] != '@' {
+			continue
+		}
+		if i > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(lowerContent[:i])
+			if !unicode.IsSpace(prev) && !unicode.IsPunct(prev) {
+				continue // e.g. email address
+			}
+		}
+
+		rest := lowerContent[i+1:]
+		var mentioned primitive.ObjectID
+		longest := 0
+		for userID, userNames := range names {
+			for _, name := range userNames {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name == "" || len(name) <= longest || !strings.HasPrefix(rest, name) {
+					continue
+				}
+				if next, _ := utf8.DecodeRuneInString(rest[len(name):]); unicode.IsLetter(next) || unicode.IsDigit(next) {
+					continue
+				}
+				mentioned, longest = userID, len(name)
+			}
+		}
+
+		if longest > 0 && !slices.Contains(mentions, mentioned) {
+			mentions = append(mentions, mentioned)
+		}
+	}
+
+	return mentions
+}
+
+// resolveMentions resolves mentions of the sender's message against other members of the conversation
+func resolveMentions(content string, conversation chatdb.Conversation, senderID primitive.ObjectID) []primitive.ObjectID {
+	if !strings.Contains(content, "@") {
+		return nil
+	}
+
+	names := make(map[primitive.ObjectID][]string)
+	memberIDs := make([]primitive.ObjectID, 0, len(conversation.Members))
+	for _, m := range conversation.Members {
+		if m.UserID == senderID {
+			continue
+		}
+		names[m.UserID] = []string{m.Nickname}
+		memberIDs = append(memberIDs, m.UserID)
+	}
+
+	if app.UsersRepo != nil && len(memberIDs) != 0 {
+		users, err := app.UsersRepo.GetUsersByIDs(memberIDs)
+		if err != nil {
+			log.Println("can not get members to resolve mentions:", err)
+		}
+		for _, u := range users {
+			names[u.ID] = append(names[u.ID], u.Name)
+		}
+	}
+
+	mentions := ParseMentions(content, names)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	return mentions
+}
+
+// shouldNotifyMessage checks if the recipient member should be notified of the message, members who muted
+// the conversation are not notified, mentioned members are only notified once by notifyMentionedUsers
+func shouldNotifyMessage(message chatdb.Message, member chatdb.Member) bool {
+	return !member.Muted && !slices.Contains(message.Mentions, member.UserID)
+}
+
+// notifyMentionedUsers notifies mentioned users via notification service,
+// mentions are notified regardless of the muted state of the members.
+func notifyMentionedUsers(message chatdb.Message) {
+	if app.Transporter == nil {
+		return
+	}
+
+	for _, userID := range message.Mentions {
+		event := transport.MentionUserEvent{
+			Event: transport.Event{Type: transport.MentionUser, Timestamp: time.Now()},
+			Payload: transport.MentionUserPayload{
+				UserID:         userID.Hex(),
+				SenderID:       message.SenderID.Hex(),
+				ConversationID: message.ConversationID.Hex(),
+				MessageID:      message.ID.Hex(),
+				Content:        message.Content,
+			},
+		}
+		payload, _ := json.Marshal(event)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		err := app.Transporter.Push(ctx, app.Transporter.ConsumerID(transport.Notification), payload)
+		cancel()
+		if err != nil {
+			log.Println("cannot push mention event to notification service:", err)
+		}
+	}
+}
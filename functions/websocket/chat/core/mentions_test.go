@@ -0,0 +1,45 @@
+package wschat
+
+import (
+	"testing"
+
+	"blinders/packages/db/chatdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseMentions(t *testing.T) {
+	anna, annaLee, bob := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	names := map[primitive.ObjectID][]string{
+		anna:    {"", "Anna"},
+		annaLee: {"Anna Lee"},
+		bob:     {"bobby", "Bob"},
+	}
+
+	assert.Equal(t,
+		[]primitive.ObjectID{annaLee, bob},
+		ParseMentions("hey @anna lee, could you and @Bob check this? @bob", names))
+	assert.Equal(t,
+		[]primitive.ObjectID{anna},
+		ParseMentions("(@Anna) what do you think", names))
+}
+
+func TestParseMentionsIgnoreInvalidMentions(t *testing.T) {
+	names := map[primitive.ObjectID][]string{primitive.NewObjectID(): {"Anna"}}
+
+	assert.Empty(t, ParseMentions("mail me at me@anna.com", names))
+	assert.Empty(t, ParseMentions("@annabelle are you there", names))
+	assert.Empty(t, ParseMentions("@ anna", names))
+}
+
+func TestShouldNotifyMessage(t *testing.T) {
+	mentioned, other := primitive.NewObjectID(), primitive.NewObjectID()
+	message := chatdb.Message{Mentions: []primitive.ObjectID{mentioned}}
+
+	assert.True(t, shouldNotifyMessage(message, chatdb.Member{UserID: other}))
+	assert.False(t, shouldNotifyMessage(message, chatdb.Member{UserID: other, Muted: true}))
+	// mentioned members are notified by the mention event only, even if they muted the conversation
+	assert.False(t, shouldNotifyMessage(message, chatdb.Member{UserID: mentioned}))
+	assert.False(t, shouldNotifyMessage(message, chatdb.Member{UserID: mentioned, Muted: true}))
+}
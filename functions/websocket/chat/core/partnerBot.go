@@ -0,0 +1,180 @@
+package wschat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"blinders/packages/db/chatdb"
+	"blinders/packages/suggest"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultPartnerBotHistorySize = 20
+	DefaultPartnerBotTimeout     = time.Second * 20
+)
+
+// PartnerBot practises with learners when no human partner is online. It replies messages
+// in individual conversations that it is a member of, its replies are stored and distributed
+// the same way as messages of users.
+type PartnerBot struct {
+	UserID    primitive.ObjectID
+	Suggester suggest.Suggester
+	// number of recent messages used to build the prompt
+	HistorySize int64
+	Timeout     time.Duration
+}
+
+func NewPartnerBot(userID primitive.ObjectID, suggester suggest.Suggester) *PartnerBot {
+	return &PartnerBot{
+		UserID:      userID,
+		Suggester:   suggester,
+		HistorySize: DefaultPartnerBotHistorySize,
+		Timeout:     DefaultPartnerBotTimeout,
+	}
+}
+
+// ShouldReply checks if the message is sent to the bot in an individual conversation
+func (b *PartnerBot) ShouldReply(message chatdb.Message, conversation chatdb.Conversation) bool {
+	if b == nil || message.SenderID == b.UserID || conversation.Type != chatdb.IndividualConversation {
+		return false
+	}
+
+	return slices.ContainsFunc(conversation.Members, func(m chatdb.Member) bool { return m.UserID == b.UserID })
+}
+
+// Reply constructs the reply of the bot to the latest message of the conversation, which must be stored
+// before calling. Latency and token usage of the reply are recorded, failed replies are recorded too.
+func (b *PartnerBot) Reply(message chatdb.Message, conversation chatdb.Conversation) (*chatdb.Message, error) {
+	start := time.Now()
+	response := chatdb.BotResponse{
+		BotID:          b.UserID,
+		ConversationID: conversation.ID,
+		ReplyTo:        message.ID,
+	}
+
+	reply, err := b.reply(message, conversation, &response)
+	response.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		response.MessageID = &reply.ID
+	}
+	if app.ChatDB.BotResponsesRepo != nil {
+		_, _ = app.ChatDB.BotResponsesRepo.InsertNewRawBotResponse(response)
+	}
+
+	return reply, err
+}
+
+func (b *PartnerBot) reply(
+	message chatdb.Message,
+	conversation chatdb.Conversation,
+	response *chatdb.BotResponse,
+) (*chatdb.Message, error) {
+	history, err := app.ChatDB.MessagesRepo.GetMessagesOfConversation(conversation.ID, b.HistorySize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get recent messages: %v", err)
+	}
+
+	// recent messages are sorted by the latest first
+	msgs := make([]suggest.Message, 0, len(*history))
+	for i := len(*history) - 1; i >= 0; i-- {
+		m := (*history)[i]
+		sender, receiver := suggest.LearnerSender, suggest.PartnerSender
+		if m.SenderID == b.UserID {
+			sender, receiver = receiver, sender
+		}
+		msgs = append(msgs, suggest.Message{
+			Sender:    sender,
+			Receiver:  receiver,
+			Content:   m.Content,
+			Timestamp: m.CreatedAt.Time().Unix(),
+		})
+	}
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("no message to reply")
+	}
+
+	if app.MatchingRepo == nil {
+		return nil, fmt.Errorf("cannot get languages of learner: matching repo is not set")
+	}
+	matchInfo, err := app.MatchingRepo.GetByUserID(message.SenderID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get languages of learner: %v", err)
+	}
+	userData, err := suggest.NewUserData(message.SenderID.Hex(), matchInfo.Native, matchInfo.Learnings)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+
+	var suggestions []string
+	if suggester, ok := b.Suggester.(suggest.UsageSuggester); ok {
+		suggestion, err := suggester.ChatCompletionWithUsage(ctx, userData, msgs, suggest.NewPartnerReplyPrompter())
+		if err != nil {
+			return nil, fmt.Errorf("cannot suggest reply: %v", err)
+		}
+		suggestions = suggestion.Suggestions
+		response.RequestTokens, response.ResponseTokens = suggestion.RequestTokens, suggestion.ResponseTokens
+	} else {
+		suggestions, err = b.Suggester.ChatCompletion(ctx, userData, msgs, suggest.NewPartnerReplyPrompter())
+		if err != nil {
+			return nil, fmt.Errorf("cannot suggest reply: %v", err)
+		}
+	}
+
+	content := ""
+	if len(suggestions) != 0 {
+		content = strings.TrimSpace(suggestions[0])
+	}
+	if content == "" {
+		return nil, fmt.Errorf("got empty reply")
+	}
+
+	reply := app.ChatDB.MessagesRepo.ConstructNewMessage(
+		b.UserID,
+		conversation.ID,
+		primitive.NilObjectID,
+		content,
+	)
+
+	return &reply, nil
+}
+
+// withPartnerBotReply forwards events of the sent message, then distributes the reply of the bot
+// after the sent message is stored
+func withPartnerBotReply(
+	messageCh <-chan *DistributeEvent,
+	message chatdb.Message,
+	conversation chatdb.Conversation,
+) <-chan *DistributeEvent {
+	dCh := make(chan *DistributeEvent)
+
+	go func() {
+		for e := <-messageCh; e != nil; e = <-messageCh {
+			dCh <- e
+		}
+
+		reply, err := app.PartnerBot.Reply(message, conversation)
+		if err != nil {
+			log.Println("partner bot failed to reply:", err)
+			dCh <- nil
+			return
+		}
+
+		replyCh := distributeNewMessage(*reply, conversation, "", "")
+		for e := <-replyCh; e != nil; e = <-replyCh {
+			dCh <- e
+		}
+		dCh <- nil
+	}()
+
+	return dCh
+}
@@ -0,0 +1,28 @@
+package wschat
+
+import (
+	"testing"
+
+	"blinders/packages/db/chatdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPartnerBotShouldReply(t *testing.T) {
+	learnerID := primitive.NewObjectID()
+	bot := NewPartnerBot(primitive.NewObjectID(), nil)
+	conversation := chatdb.Conversation{
+		Type:    chatdb.IndividualConversation,
+		Members: []chatdb.Member{{UserID: learnerID}, {UserID: bot.UserID}},
+	}
+
+	assert.True(t, bot.ShouldReply(chatdb.Message{SenderID: learnerID}, conversation))
+	assert.False(t, bot.ShouldReply(chatdb.Message{SenderID: bot.UserID}, conversation))
+
+	conversation.Members = []chatdb.Member{{UserID: learnerID}, {UserID: primitive.NewObjectID()}}
+	assert.False(t, bot.ShouldReply(chatdb.Message{SenderID: learnerID}, conversation))
+
+	var disabled *PartnerBot
+	assert.False(t, disabled.ShouldReply(chatdb.Message{SenderID: learnerID}, conversation))
+}
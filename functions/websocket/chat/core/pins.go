@@ -0,0 +1,101 @@
+package wschat
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"blinders/packages/db/chatdb"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HandlePinMessage pins or unpins a message of the conversation,
+// the updated pins are broadcast to all sessions of the conversation members.
+func HandlePinMessage(
+	rawUserID string,
+	connectionID string,
+	payload UserPinMessagePayload,
+) (<-chan *DistributeEvent, error) {
+	dCh := make(chan *DistributeEvent)
+
+	userID, _ := primitive.ObjectIDFromHex(rawUserID)
+	conversationID, err := primitive.ObjectIDFromHex(payload.ConversationID)
+	if err != nil {
+		return dCh, fmt.Errorf("invalid conversationId: %s", payload.ConversationID)
+	}
+	messageID, err := primitive.ObjectIDFromHex(payload.MessageID)
+	if err != nil {
+		return dCh, fmt.Errorf("invalid messageId: %s", payload.MessageID)
+	}
+
+	pinned := payload.Type != UserUnpinMessage
+	var conversation *chatdb.Conversation
+	if pinned {
+		message, err := app.ChatDB.MessagesRepo.GetMessageByID(messageID)
+		if err != nil || message.ConversationID != conversationID {
+			return dCh, fmt.Errorf("message %s is not in conversation %s", payload.MessageID, payload.ConversationID)
+		}
+		conversation, err = app.ChatDB.ConversationsRepo.PinMessage(conversationID, messageID, userID)
+		if err != nil {
+			return dCh, fmt.Errorf("failed to pin message: %v", err)
+		}
+	} else {
+		conversation, err = app.ChatDB.ConversationsRepo.UnpinMessage(conversationID, messageID, userID)
+		if err != nil {
+			return dCh, fmt.Errorf("failed to unpin message: %v", err)
+		}
+	}
+
+	event := ServerUpdatePinsPayload{
+		ChatEvent:      ChatEvent{Type: ServerUpdatePins},
+		ConversationID: conversationID.Hex(),
+		Pinned:         pinned,
+		MessageID:      messageID.Hex(),
+		UpdatedBy:      rawUserID,
+		Pins:           conversation.Pins,
+	}
+	if event.Pins == nil {
+		event.Pins = make([]chatdb.PinnedMessage, 0)
+	}
+
+	go func() {
+		distributePinsToMembers(event, *conversation, connectionID, payload.ResolveID, dCh)
+		dCh <- nil
+	}()
+
+	return dCh, nil
+}
+
+func distributePinsToMembers(
+	event ServerUpdatePinsPayload,
+	conversation chatdb.Conversation,
+	curConnID string,
+	resolveID string,
+	dCh chan *DistributeEvent,
+) {
+	wg := sync.WaitGroup{}
+	for _, m := range conversation.Members {
+		wg.Add(1)
+		go func(m chatdb.Member) {
+			defer wg.Done()
+			sessions, err := app.Session.GetSessions(m.UserID.Hex())
+			if err != nil {
+				log.Println("failed to query sessions for user", m.UserID.Hex())
+				return
+			}
+
+			for _, s := range sessions {
+				connectionID := strings.Split(s, ":")[1]
+				payload := event
+				if connectionID == curConnID {
+					payload.ResolveID = resolveID
+				}
+				dCh <- &DistributeEvent{ConnectionID: connectionID, Payload: payload}
+			}
+		}(m)
+	}
+
+	wg.Wait()
+}
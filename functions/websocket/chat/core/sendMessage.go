@@ -3,6 +3,7 @@ package wschat
 import (
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"sync"
 
@@ -17,56 +18,140 @@ func HandleSendMessage(
 	payload UserSendMessagePayload,
 ) (<-chan *DistributeEvent, error) {
 	dCh := make(chan *DistributeEvent)
-	wg := sync.WaitGroup{}
 
 	userID, _ := primitive.ObjectIDFromHex(rawUserID)
-	conversationID, err := primitive.ObjectIDFromHex(payload.ConversationID)
+	conversation, repliedMessage, err := validateSendMessagePayload(userID, payload)
 	if err != nil {
-		return dCh, fmt.Errorf("invalid conversationId: %s", payload.ConversationID)
+		return dCh, err
+	}
+
+	// slash commands are private, they are not stored nor sent to other members
+	if cmd, ok := chatdb.ParseCommand(payload.Content); ok {
+		return HandleCommand(userID, connectionID, conversation.ID, repliedMessage, *cmd, payload.ResolveID), nil
+	}
+
+	message := constructMessageOfUser(userID, *conversation, repliedMessage, payload.Content)
+	messageCh := distributeNewMessage(message, *conversation, connectionID, payload.ResolveID)
+
+	if app.PartnerBot.ShouldReply(message, *conversation) {
+		return withPartnerBotReply(messageCh, message, *conversation), nil
 	}
 
+	return messageCh, nil
+}
+
+// validateSendMessagePayload returns the conversation and the replied message (nil if not replying) of the payload
+// if the user is a member of the conversation and the replied message is in the conversation.
+func validateSendMessagePayload(
+	userID primitive.ObjectID,
+	payload UserSendMessagePayload,
+) (*chatdb.Conversation, *chatdb.Message, error) {
 	var replyTo primitive.ObjectID
+	conversationID, err := primitive.ObjectIDFromHex(payload.ConversationID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid conversationId: %s", payload.ConversationID)
+	}
+
 	if payload.ReplyTo != "" {
 		replyTo, err = primitive.ObjectIDFromHex(payload.ReplyTo)
 		if err != nil {
-			return dCh, fmt.Errorf("invalid replyTo: %s", payload.ReplyTo)
+			return nil, nil, fmt.Errorf("invalid replyTo: %s", payload.ReplyTo)
 		}
 	}
 
 	conversation, err := queryConversationOfUser(conversationID, userID)
 	if err != nil {
-		return dCh, fmt.Errorf("failed to query conversation: %v", err)
+		return nil, nil, fmt.Errorf("failed to query conversation: %v", err)
+	}
+	if conversation.ReadOnly {
+		return nil, nil, fmt.Errorf("conversation %s is read only", conversationID.Hex())
 	}
 
-	if !replyTo.IsZero() {
-		err := checkValidReplyTo(replyTo, conversationID)
-		if err != nil {
-			return dCh, fmt.Errorf("cannot reply to message %s", payload.ReplyTo)
-		}
+	if replyTo.IsZero() {
+		return conversation, nil, nil
+	}
+
+	repliedMessage, err := checkValidReplyTo(replyTo, conversationID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot reply to message %s", payload.ReplyTo)
+	}
+
+	return conversation, repliedMessage, nil
+}
+
+func constructMessageOfUser(
+	userID primitive.ObjectID,
+	conversation chatdb.Conversation,
+	repliedMessage *chatdb.Message,
+	content string,
+) chatdb.Message {
+	message := constructReplyMessage(userID, conversation.ID, repliedMessage, content)
+	message.Mentions = resolveMentions(content, conversation, userID)
+
+	return message
+}
+
+// constructReplyMessage constructs a new message which joins the thread of the replied message
+func constructReplyMessage(
+	userID primitive.ObjectID,
+	conversationID primitive.ObjectID,
+	repliedMessage *chatdb.Message,
+	content string,
+) chatdb.Message {
+	var replyTo primitive.ObjectID
+	if repliedMessage != nil {
+		replyTo = repliedMessage.ID
 	}
 
 	message := app.ChatDB.MessagesRepo.ConstructNewMessage(
 		userID,
 		conversationID,
 		replyTo,
-		payload.Content,
+		content,
 	)
+	if repliedMessage != nil {
+		threadID := repliedMessage.ThreadRootID()
+		message.ThreadID = &threadID
+	}
+
+	return message
+}
+
+// distributeNewMessage stores the new message and distributes it to all sessions of conversation members,
+// the sender connection receives an ack message instead. Empty connectionID distributes to all sender sessions.
+func distributeNewMessage(
+	message chatdb.Message,
+	conversation chatdb.Conversation,
+	connectionID string,
+	resolveID string,
+) <-chan *DistributeEvent {
+	dCh := make(chan *DistributeEvent)
+	wg := sync.WaitGroup{}
+
+	// messages which are not sent from a connection (e.g. scheduled messages) do not need ack
+	if connectionID != "" {
+		wg.Add(1)
+		go func() {
+			distributeAckMessage(message, connectionID, resolveID, dCh)
+			wg.Done()
+		}()
+	}
 
 	wg.Add(1)
 	go func() {
-		distributeAckMessage(message, connectionID, payload.ResolveID, dCh)
+		distributeMessageToRecipients(message, conversation, dCh)
 		wg.Done()
 	}()
 
 	wg.Add(1)
 	go func() {
-		distributeMessageToRecipients(message, *conversation, dCh)
+		distributeMessageToAnotherSenderSessions(message, message.SenderID.Hex(), connectionID, dCh)
 		wg.Done()
 	}()
 
 	wg.Add(1)
 	go func() {
-		distributeMessageToAnotherSenderSessions(message, rawUserID, connectionID, dCh)
+		notifyMentionedUsers(message)
 		wg.Done()
 	}()
 
@@ -77,6 +162,12 @@ func HandleSendMessage(
 		if err != nil {
 			log.Fatalln("[dangerous] failed to insert message", err)
 		}
+		if message.ThreadID != nil {
+			err := app.ChatDB.MessagesRepo.IncreaseReplyCount(*message.ThreadID, message.CreatedAt)
+			if err != nil {
+				log.Println("failed to update thread of message", message.ID.Hex(), err)
+			}
+		}
 		wg.Done()
 	}()
 
@@ -85,7 +176,7 @@ func HandleSendMessage(
 		dCh <- nil
 	}()
 
-	return dCh, nil
+	return dCh
 }
 
 // query conversation by id
@@ -112,15 +203,15 @@ func queryConversationOfUser(
 	)
 }
 
-func checkValidReplyTo(replyTo primitive.ObjectID, conversationID primitive.ObjectID) error {
+func checkValidReplyTo(replyTo primitive.ObjectID, conversationID primitive.ObjectID) (*chatdb.Message, error) {
 	repliedMessage, err := app.ChatDB.MessagesRepo.GetMessageByID(replyTo)
 	if err != nil {
-		return err
+		return nil, err
 	} else if repliedMessage.ConversationID != conversationID {
-		return fmt.Errorf("reply to message %s is not in conversation %s", replyTo.Hex(), conversationID.Hex())
+		return nil, fmt.Errorf("reply to message %s is not in conversation %s", replyTo.Hex(), conversationID.Hex())
 	}
 
-	return nil
+	return &repliedMessage, nil
 }
 
 func distributeAckMessage(
@@ -159,13 +250,19 @@ func distributeMessageToRecipients(
 				return
 			}
 
+			eventType := ServerSendMessage
+			if slices.Contains(message.Mentions, m.UserID) {
+				eventType = ServerSendMentionMessage
+			}
+
 			for _, s := range sessions {
 				connectionID := strings.Split(s, ":")[1]
 				dCh <- &DistributeEvent{
 					ConnectionID: connectionID,
 					Payload: ServerSendMessagePayload{
-						ChatEvent: ChatEvent{Type: ServerSendMessage},
+						ChatEvent: ChatEvent{Type: eventType},
 						Message:   message,
+						Notify:    shouldNotifyMessage(message, m),
 					},
 				}
 			}
@@ -200,11 +200,13 @@ func TestSendMessageWithDistribution(t *testing.T) {
 			assert.Equal(t, ServerSendMessage, payload.Type)
 			assert.Equal(t, conversation.ID, payload.Message.ConversationID)
 			assert.Equal(t, content, payload.Message.Content)
+			assert.True(t, payload.Notify)
 		case r2connID:
 			payload := de.Payload.(ServerSendMessagePayload)
 			assert.Equal(t, ServerSendMessage, payload.Type)
 			assert.Equal(t, conversation.ID, payload.Message.ConversationID)
 			assert.Equal(t, content, payload.Message.Content)
+			assert.True(t, payload.Notify)
 		}
 
 	}
@@ -11,13 +11,21 @@ import (
 	wschat "blinders/functions/websocket/chat/core"
 	"blinders/packages/apigateway"
 	"blinders/packages/db/chatdb"
+	"blinders/packages/db/matchingdb"
+	"blinders/packages/db/usersdb"
 	dbutils "blinders/packages/db/utils"
 	"blinders/packages/session"
+	"blinders/packages/suggest"
+	"blinders/packages/translate"
+	"blinders/packages/transport"
 	"blinders/packages/utils"
+	suggestcore "blinders/services/suggest/core"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/sashabaranov/go-openai"
 )
 
 var APIGatewayClient *apigateway.Client
@@ -31,12 +39,42 @@ func init() {
 		log.Fatal(err)
 	}
 
-	wschat.InitChatApp(sessionManager, chatdb.NewChatDB(chatDB))
+	usersDB, err := dbutils.InitMongoDatabaseFromEnv("USERS")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	matchingDB, err := dbutils.InitMongoDatabaseFromEnv("MATCHING")
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		log.Fatal("failed to load aws config:", err)
 	}
+
+	app := wschat.InitChatApp(sessionManager, chatdb.NewChatDB(chatDB))
+	app.UsersRepo = usersdb.NewUsersRepo(usersDB)
+	app.MatchingRepo = matchingdb.NewMatchingRepo(matchingDB)
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		bot, err := app.UsersRepo.GetOrInsertPartnerBot()
+		if err != nil {
+			log.Fatal("failed to get partner bot:", err)
+		}
+		suggester, _ := suggest.NewGPTSuggester(
+			openai.NewClient(apiKey),
+			suggest.WithNChat(1),
+			suggest.WithMaxTokens(suggest.PartnerReplyMaxTokens),
+		)
+		app.PartnerBot = wschat.NewPartnerBot(bot.ID, suggester)
+	}
+	app.Translator = translate.YandexTranslator{APIKey: os.Getenv("YANDEX_API_KEY")}
+	app.Explainer = suggestcore.BedrockExplainer{Client: bedrockruntime.NewFromConfig(cfg)}
+	app.Transporter = transport.NewLambdaTransportWithConsumers(cfg, transport.ConsumerMap{
+		transport.CollectingPush: os.Getenv("COLLECTING_PUSH_FUNCTION_NAME"),
+		transport.Notification:   os.Getenv("NOTIFICATION_FUNCTION_NAME"),
+	})
 	cer := apigateway.CustomEndpointResolve{
 		Domain:     os.Getenv("API_GATEWAY_DOMAIN"),
 		PathPrefix: os.Getenv("API_GATEWAY_PATH_PREFIX"),
@@ -82,32 +120,46 @@ func HandleRequest(
 			break
 		}
 
-		wg := sync.WaitGroup{}
-		for {
-			d := <-dCh
-			if d == nil {
-				log.Println("distribute message channel closed")
-				break
-			}
+		publishDistributeEvents(ctx, dCh)
+		log.Println("message sent")
+	case wschat.UserSendCorrection:
+		payload, err := utils.ParseJSON[wschat.UserSendCorrectionPayload]([]byte(req.Body))
+		if err != nil {
+			log.Println("invalid send correction event:", err)
+			_ = APIGatewayClient.Publish(ctx, connectionID, []byte("invalid send correction event"))
+			break
+		}
 
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				data, err := json.Marshal(d.Payload)
-				if err != nil {
-					log.Println("can not marshal data:", err)
-					return
-				}
-
-				err = APIGatewayClient.Publish(ctx, d.ConnectionID, data)
-				if err != nil {
-					log.Println("can not publish message:", err)
-				}
-			}()
+		dCh, err := wschat.HandleSendCorrection(userID, connectionID, *payload)
+		if err != nil {
+			log.Println("failed to send correction:", err)
+			_ = APIGatewayClient.Publish(
+				ctx,
+				connectionID,
+				[]byte("invalid payload to send correction"),
+			)
+			break
 		}
 
-		wg.Wait()
-		log.Println("message sent")
+		publishDistributeEvents(ctx, dCh)
+		log.Println("correction sent")
+	case wschat.UserPinMessage, wschat.UserUnpinMessage:
+		payload, err := utils.ParseJSON[wschat.UserPinMessagePayload]([]byte(req.Body))
+		if err != nil {
+			log.Println("invalid pin message event:", err)
+			_ = APIGatewayClient.Publish(ctx, connectionID, []byte("invalid pin message event"))
+			break
+		}
+
+		dCh, err := wschat.HandlePinMessage(userID, connectionID, *payload)
+		if err != nil {
+			log.Println("failed to update pins:", err)
+			_ = APIGatewayClient.Publish(ctx, connectionID, []byte(err.Error()))
+			break
+		}
+
+		publishDistributeEvents(ctx, dCh)
+		log.Println("pins updated")
 	default:
 		log.Println("not support this event:", req.Body)
 		_ = APIGatewayClient.Publish(ctx, connectionID, []byte("not support this event"))
@@ -116,6 +168,35 @@ func HandleRequest(
 	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
 }
 
+// publishDistributeEvents publishes events to their connections until the channel sends nil
+func publishDistributeEvents(ctx context.Context, dCh <-chan *wschat.DistributeEvent) {
+	wg := sync.WaitGroup{}
+	for {
+		d := <-dCh
+		if d == nil {
+			log.Println("distribute message channel closed")
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := json.Marshal(d.Payload)
+			if err != nil {
+				log.Println("can not marshal data:", err)
+				return
+			}
+
+			err = APIGatewayClient.Publish(ctx, d.ConnectionID, data)
+			if err != nil {
+				log.Println("can not publish message:", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
 func main() {
 	lambda.Start(HandleRequest)
 }
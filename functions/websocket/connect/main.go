@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"log"
+	"strings"
 
+	"blinders/packages/auth"
 	"blinders/packages/session"
 	"blinders/packages/utils"
 
@@ -35,7 +37,16 @@ func HandleRequest(
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "failed to add session"}, nil
 	}
 
-	return events.APIGatewayProxyResponse{StatusCode: 200, Body: "connected"}, nil
+	response := events.APIGatewayProxyResponse{StatusCode: 200, Body: "connected"}
+	// browsers close the connection if the subprotocol of the ticket is not echoed
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, auth.TicketHeader) {
+			if _, protocol := auth.TicketFromProtocols(value); protocol != "" {
+				response.Headers = map[string]string{auth.TicketHeader: protocol}
+			}
+		}
+	}
+	return response, nil
 }
 
 func main() {
@@ -48,26 +48,22 @@ func HandleRequest(ctx context.Context, event transport.Event) error {
 			log.Println("can not parse request payload:", err)
 			return err
 		}
-		userConIDs, err := SessionManager.GetSessions(event.Payload.UserID)
+		return publishToUserSessions(ctx, event.Payload.UserID, event)
+	case transport.RemoveFriend:
+		event, err := utils.JSONConvert[transport.RemoveFriendEvent](event)
 		if err != nil {
-			log.Println("can not get session:", err)
+			log.Println("can not parse request payload:", err)
 			return err
 		}
-
-		eventBytes, _ := json.Marshal(event)
-		wg := sync.WaitGroup{}
-		for _, conID := range userConIDs {
-			wg.Add(1)
-			go func(conID string, event []byte) {
-				conID = strings.Split(conID, ":")[1]
-				err = APIGatewayClient.Publish(ctx, conID, event)
-				if err != nil {
-					log.Println("failed to publish:", err)
-				}
-				wg.Done()
-			}(conID, eventBytes)
+		return publishToUserSessions(ctx, event.Payload.UserID, event)
+	case transport.MentionUser:
+		// mentions are notified even if the conversation is muted by the user
+		event, err := utils.JSONConvert[transport.MentionUserEvent](event)
+		if err != nil {
+			log.Println("can not parse request payload:", err)
+			return err
 		}
-		wg.Wait()
+		return publishToUserSessions(ctx, event.Payload.UserID, event)
 	default:
 		log.Print("does not support event type:", event.Type)
 	}
@@ -75,6 +71,31 @@ func HandleRequest(ctx context.Context, event transport.Event) error {
 	return nil
 }
 
+func publishToUserSessions(ctx context.Context, userID string, event any) error {
+	userConIDs, err := SessionManager.GetSessions(userID)
+	if err != nil {
+		log.Println("can not get session:", err)
+		return err
+	}
+
+	eventBytes, _ := json.Marshal(event)
+	wg := sync.WaitGroup{}
+	for _, conID := range userConIDs {
+		wg.Add(1)
+		go func(conID string, event []byte) {
+			conID = strings.Split(conID, ":")[1]
+			err := APIGatewayClient.Publish(ctx, conID, event)
+			if err != nil {
+				log.Println("failed to publish:", err)
+			}
+			wg.Done()
+		}(conID, eventBytes)
+	}
+	wg.Wait()
+
+	return nil
+}
+
 func main() {
 	lambda.Start(HandleRequest)
 }
@@ -0,0 +1,92 @@
+/*
+This function dispatches due scheduled messages to conversations.
+On AWS, it is triggered periodically by an EventBridge schedule,
+otherwise it runs an in-process ticker for local development.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	wschat "blinders/functions/websocket/chat/core"
+	"blinders/packages/apigateway"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/usersdb"
+	dbutils "blinders/packages/db/utils"
+	"blinders/packages/session"
+	"blinders/packages/transport"
+	"blinders/packages/utils"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+var dispatcher wschat.Dispatcher
+
+func init() {
+	redisClient := utils.NewRedisClientFromEnv(context.Background())
+	sessionManager := session.NewManager(redisClient)
+
+	chatDB, err := dbutils.InitMongoDatabaseFromEnv("CHAT")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	usersDB, err := dbutils.InitMongoDatabaseFromEnv("USERS")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatal("failed to load aws config:", err)
+	}
+
+	app := wschat.InitChatApp(sessionManager, chatdb.NewChatDB(chatDB))
+	app.UsersRepo = usersdb.NewUsersRepo(usersDB)
+	app.Transporter = transport.NewLambdaTransportWithConsumers(cfg, transport.ConsumerMap{
+		transport.Notification: os.Getenv("NOTIFICATION_FUNCTION_NAME"),
+	})
+
+	cer := apigateway.CustomEndpointResolve{
+		Domain:     os.Getenv("API_GATEWAY_DOMAIN"),
+		PathPrefix: os.Getenv("API_GATEWAY_PATH_PREFIX"),
+	}
+	apiGatewayClient := apigateway.NewClient(context.Background(), cfg, cer)
+
+	dispatcher = wschat.NewScheduledMessageDispatcher(
+		func(ctx context.Context, connectionID string, payload any) error {
+			data, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			return apiGatewayClient.Publish(ctx, connectionID, data)
+		},
+	)
+}
+
+func HandleRequest(ctx context.Context) error {
+	sent, err := dispatcher.Dispatch(ctx)
+	log.Printf("dispatched %d scheduled messages\n", sent)
+
+	return err
+}
+
+func main() {
+	// this variable is set by lambda runtime
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		lambda.Start(HandleRequest)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Println("running scheduled message dispatcher in process")
+	wschat.NewTickerDispatcher(dispatcher, time.Second*10).Run(ctx)
+}
@@ -41,11 +41,13 @@ resource "aws_apigatewayv2_api_mapping" "websocket_api_v1" {
 }
 
 resource "aws_apigatewayv2_authorizer" "websocket_authorizer" {
-  name             = "${var.project.name}-websocket-authorizer-${var.project.environment}"
-  api_id           = aws_apigatewayv2_api.websocket_api.id
-  authorizer_type  = "REQUEST"
-  authorizer_uri   = aws_lambda_function.ws_authorizer.invoke_arn
-  identity_sources = ["route.request.querystring.token"]
+  name            = "${var.project.name}-websocket-authorizer-${var.project.environment}"
+  api_id          = aws_apigatewayv2_api.websocket_api.id
+  authorizer_type = "REQUEST"
+  authorizer_uri  = aws_lambda_function.ws_authorizer.invoke_arn
+  # no identity sources, connections carry the token or the ticket in the query string or the
+  # Sec-WebSocket-Protocol header, which are checked by the authorizer. Results are not cached
+  # since tickets are single-use.
 }
 
 output "http-api-endpoint" {
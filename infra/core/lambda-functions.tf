@@ -149,6 +149,11 @@ resource "aws_lambda_function" "ws_authorizer" {
     variables = {
       ENVIRONMENT : var.project.environment
 
+      REDIS_HOST : local.envs.REDIS_HOST
+      REDIS_PORT : local.envs.REDIS_PORT
+      REDIS_USERNAME : local.envs.REDIS_USERNAME
+      REDIS_PASSWORD : local.envs.REDIS_PASSWORD
+
       USERS_MONGO_DATABASE : local.envs.USERS_MONGO_DATABASE
       USERS_MONGO_DATABASE_URL : local.envs.USERS_MONGO_DATABASE_URL
     }
@@ -190,6 +195,7 @@ resource "aws_lambda_function" "ws_disconnect" {
 resource "aws_lambda_function" "ws_chat" {
   function_name    = "${var.project.name}-ws-chat-${var.project.environment}"
   filename         = "../../dist/wschat-${var.project.environment}.zip"
+  timeout          = 30
   handler          = "bootstrap"
   role             = aws_iam_role.lambda_role.arn
   runtime          = "provided.al2"
@@ -211,6 +217,57 @@ resource "aws_lambda_function" "ws_chat" {
 
       CHAT_MONGO_DATABASE : local.envs.CHAT_MONGO_DATABASE
       CHAT_MONGO_DATABASE_URL : local.envs.CHAT_MONGO_DATABASE_URL
+
+      USERS_MONGO_DATABASE : local.envs.USERS_MONGO_DATABASE
+      USERS_MONGO_DATABASE_URL : local.envs.USERS_MONGO_DATABASE_URL
+
+      MATCHING_MONGO_DATABASE : local.envs.MATCHING_MONGO_DATABASE
+      MATCHING_MONGO_DATABASE_URL : local.envs.MATCHING_MONGO_DATABASE_URL
+
+      YANDEX_API_KEY : local.envs.YANDEX_API_KEY
+      OPENAI_API_KEY : local.envs.OPENAI_API_KEY
+
+      COLLECTING_PUSH_FUNCTION_NAME : aws_lambda_function.collecting-push.function_name
+      NOTIFICATION_FUNCTION_NAME : aws_lambda_function.notification.function_name
+    }
+  }
+
+  tags = {
+    project     = var.project.name
+    environment = var.project.environment
+  }
+}
+
+resource "aws_lambda_function" "ws_scheduler" {
+  function_name    = "${var.project.name}-ws-scheduler-${var.project.environment}"
+  filename         = "../../dist/wsscheduler-${var.project.environment}.zip"
+  handler          = "bootstrap"
+  role             = aws_iam_role.lambda_role.arn
+  runtime          = "provided.al2"
+  architectures    = ["arm64"]
+  timeout          = 50
+  depends_on       = [aws_iam_role_policy_attachment.attach_iam_policy_to_iam_role]
+  source_code_hash = filebase64sha256("../../dist/wsscheduler-${var.project.environment}.zip")
+
+  environment {
+    variables = {
+      ENVIRONMENT : var.project.environment
+
+      REDIS_HOST : local.envs.REDIS_HOST
+      REDIS_PORT : local.envs.REDIS_PORT
+      REDIS_USERNAME : local.envs.REDIS_USERNAME
+      REDIS_PASSWORD : local.envs.REDIS_PASSWORD
+
+      API_GATEWAY_DOMAIN : local.envs.API_GATEWAY_DOMAIN
+      API_GATEWAY_PATH_PREFIX : local.envs.API_GATEWAY_PATH_PREFIX
+
+      CHAT_MONGO_DATABASE : local.envs.CHAT_MONGO_DATABASE
+      CHAT_MONGO_DATABASE_URL : local.envs.CHAT_MONGO_DATABASE_URL
+
+      USERS_MONGO_DATABASE : local.envs.USERS_MONGO_DATABASE
+      USERS_MONGO_DATABASE_URL : local.envs.USERS_MONGO_DATABASE_URL
+
+      NOTIFICATION_FUNCTION_NAME : aws_lambda_function.notification.function_name
     }
   }
 
@@ -220,6 +277,24 @@ resource "aws_lambda_function" "ws_chat" {
   }
 }
 
+resource "aws_cloudwatch_event_rule" "ws_scheduler" {
+  name                = "${var.project.name}-ws-scheduler-${var.project.environment}"
+  schedule_expression = "rate(1 minute)"
+}
+
+resource "aws_cloudwatch_event_target" "ws_scheduler" {
+  rule = aws_cloudwatch_event_rule.ws_scheduler.name
+  arn  = aws_lambda_function.ws_scheduler.arn
+}
+
+resource "aws_lambda_permission" "ws_scheduler" {
+  statement_id  = "AllowExecutionFromEventBridge"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.ws_scheduler.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.ws_scheduler.arn
+}
+
 resource "aws_lambda_function" "rest" {
   function_name    = "${var.project.name}-rest-api-${var.project.environment}"
   filename         = "../../dist/rest-${var.project.environment}.zip"
@@ -234,6 +309,11 @@ resource "aws_lambda_function" "rest" {
     variables = {
       ENVIRONMENT : var.project.environment
 
+      REDIS_HOST : local.envs.REDIS_HOST
+      REDIS_PORT : local.envs.REDIS_PORT
+      REDIS_USERNAME : local.envs.REDIS_USERNAME
+      REDIS_PASSWORD : local.envs.REDIS_PASSWORD
+
       USERS_MONGO_DATABASE : local.envs.USERS_MONGO_DATABASE
       USERS_MONGO_DATABASE_URL : local.envs.USERS_MONGO_DATABASE_URL
 
@@ -244,7 +324,10 @@ resource "aws_lambda_function" "rest" {
       MATCHING_MONGO_DATABASE_URL : local.envs.MATCHING_MONGO_DATABASE_URL
 
       NOTIFICATION_FUNCTION_NAME : aws_lambda_function.notification.function_name,
-      EXPLORE_FUNCTION_NAME : aws_lambda_function.explore.function_name
+      EXPLORE_FUNCTION_NAME : aws_lambda_function.explore.function_name,
+      ACCOUNT_FUNCTION_NAME : aws_lambda_function.account.function_name
+
+      PRIVATE_BUCKET : aws_s3_bucket.private.id
     }
   }
 
@@ -390,9 +473,17 @@ resource "aws_lambda_function" "practice" {
       USERS_MONGO_DATABASE : local.envs.USERS_MONGO_DATABASE
       USERS_MONGO_DATABASE_URL : local.envs.USERS_MONGO_DATABASE_URL
 
+      MATCHING_MONGO_DATABASE : local.envs.MATCHING_MONGO_DATABASE
+      MATCHING_MONGO_DATABASE_URL : local.envs.MATCHING_MONGO_DATABASE_URL
+
       PRACTICE_MONGO_DATABASE : local.envs.PRACTICE_MONGO_DATABASE
       PRACTICE_MONGO_DATABASE_URL : local.envs.PRACTICE_MONGO_DATABASE_URL
 
+      CHAT_MONGO_DATABASE : local.envs.CHAT_MONGO_DATABASE
+      CHAT_MONGO_DATABASE_URL : local.envs.CHAT_MONGO_DATABASE_URL
+
+      OPENAI_API_KEY : local.envs.OPENAI_API_KEY
+
       COLLECTING_GET_FUNCTION_NAME : aws_lambda_function.collecting-get.function_name
       COLLECTING_PUSH_FUNCTION_NAME : aws_lambda_function.collecting-push.function_name
     }
@@ -404,6 +495,52 @@ resource "aws_lambda_function" "practice" {
   }
 }
 
+# account jobs run asynchronously and are resumed from the last completed step on retries
+resource "aws_lambda_function" "account" {
+  function_name    = "${var.project.name}-account-${var.project.environment}"
+  filename         = "../../dist/account-${var.project.environment}.zip"
+  handler          = "bootstrap"
+  role             = aws_iam_role.lambda_role.arn
+  runtime          = "provided.al2"
+  architectures    = ["arm64"]
+  timeout          = 900
+  depends_on       = [aws_iam_role_policy_attachment.attach_iam_policy_to_iam_role]
+  source_code_hash = filebase64sha256("../../dist/account-${var.project.environment}.zip")
+
+  environment {
+    variables = {
+      ENVIRONMENT : var.project.environment
+
+      REDIS_HOST : local.envs.REDIS_HOST
+      REDIS_PORT : local.envs.REDIS_PORT
+      REDIS_USERNAME : local.envs.REDIS_USERNAME
+      REDIS_PASSWORD : local.envs.REDIS_PASSWORD
+
+      USERS_MONGO_DATABASE : local.envs.USERS_MONGO_DATABASE
+      USERS_MONGO_DATABASE_URL : local.envs.USERS_MONGO_DATABASE_URL
+
+      CHAT_MONGO_DATABASE : local.envs.CHAT_MONGO_DATABASE
+      CHAT_MONGO_DATABASE_URL : local.envs.CHAT_MONGO_DATABASE_URL
+
+      MATCHING_MONGO_DATABASE : local.envs.MATCHING_MONGO_DATABASE
+      MATCHING_MONGO_DATABASE_URL : local.envs.MATCHING_MONGO_DATABASE_URL
+
+      COLLECTING_MONGO_DATABASE : local.envs.COLLECTING_MONGO_DATABASE
+      COLLECTING_MONGO_DATABASE_URL : local.envs.COLLECTING_MONGO_DATABASE_URL
+
+      PRACTICE_MONGO_DATABASE : local.envs.PRACTICE_MONGO_DATABASE
+      PRACTICE_MONGO_DATABASE_URL : local.envs.PRACTICE_MONGO_DATABASE_URL
+
+      PRIVATE_BUCKET : aws_s3_bucket.private.id
+    }
+  }
+
+  tags = {
+    project     = var.project.name
+    environment = var.project.environment
+  }
+}
+
 resource "aws_lambda_function" "authenticate" {
   function_name    = "${var.project.name}-authenticate-${var.project.environment}"
   filename         = "../../dist/authenticate-${var.project.environment}.zip"
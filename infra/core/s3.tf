@@ -0,0 +1,40 @@
+# archives of account exports and feedback screenshots are private, they are downloaded by users and
+# moderators through the rest api
+resource "aws_s3_bucket" "private" {
+  bucket = "${var.project.name}-private-${var.project.environment}"
+
+  tags = {
+    project     = var.project.name
+    environment = var.project.environment
+  }
+}
+
+resource "aws_s3_bucket_public_access_block" "private" {
+  bucket                  = aws_s3_bucket.private.id
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
+resource "aws_s3_bucket_lifecycle_configuration" "private" {
+  bucket = aws_s3_bucket.private.id
+
+  # screenshots are kept with their feedback, only archives of exports expire
+  rule {
+    id     = "expire-exports"
+    status = "Enabled"
+
+    filter {
+      prefix = "exports/"
+    }
+
+    expiration {
+      days = 7
+    }
+
+    abort_incomplete_multipart_upload {
+      days_after_initiation = 1
+    }
+  }
+}
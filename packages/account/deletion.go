@@ -0,0 +1,111 @@
+package account
+
+import (
+	"context"
+	"fmt"
+
+	"blinders/packages/auth"
+	"blinders/packages/db/usersdb"
+	"blinders/packages/explore"
+	"blinders/packages/session"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DeletionSteps removes or anonymizes data of the user in every database. Messages are anonymized instead of
+// deleted to keep conversations of other members readable, the user document is deleted at last so the job
+// could still be related to the user until it is completed. The firebase account of the user is not deleted.
+func (r Runner) DeletionSteps() []Step {
+	return []Step{
+		{Name: "matching-profile", Run: r.deleteMatchingProfile},
+		{Name: "match-embedding", Run: r.deleteRedisKey(explore.CreateMatchKeyWithUserID)},
+		{Name: "sessions", Run: r.deleteRedisKey(session.ConstructUserKey)},
+		{Name: "friend-requests", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.UsersDB.FriendRequestsRepo.DeleteFriendRequestsOfUser(job.UserID)
+		}},
+		{Name: "friends", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.UsersDB.UsersRepo.RemoveUserFromFriendLists(job.UserID)
+		}},
+		{Name: "scheduled-messages", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.ChatDB.ScheduledMessagesRepo.DeleteScheduledMessagesOfSender(job.UserID)
+		}},
+		{Name: "messages", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.ChatDB.MessagesRepo.AnonymizeMessagesOfSender(job.UserID)
+		}},
+		{Name: "conversations", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.ChatDB.ConversationsRepo.RemoveMemberFromConversations(job.UserID)
+		}},
+		{Name: "explain-logs", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.CollectingDB.ExplainLogsRepo.DeleteLogsOfUser(job.UserID)
+		}},
+		{Name: "translate-logs", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.CollectingDB.TranslateLogsRepo.DeleteLogsOfUser(job.UserID)
+		}},
+		{Name: "flashcards", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.PracticeDB.FlashcardsRepo.DeleteCollectionsOfUser(job.UserID)
+		}},
+		{Name: "practice-snapshots", Run: func(_ context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.PracticeDB.SnapshotsRepo.DeleteSnapshotsOfUser(job.UserID)
+		}},
+		{Name: "feedback", Run: r.anonymizeFeedback},
+		{Name: "user", Run: r.deleteUser},
+	}
+}
+
+func (r Runner) deleteMatchingProfile(_ context.Context, job usersdb.AccountJob) (int64, error) {
+	_, err := r.MatchingRepo.DropByUserID(job.UserID)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// anonymizeFeedback deletes screenshots of feedback of the user from the private storage before they are
+// unset, a failed deletion is retried since the feedback still refers to the screenshots
+func (r Runner) anonymizeFeedback(ctx context.Context, job usersdb.AccountJob) (int64, error) {
+	keys, err := r.UsersDB.FeedbackRepo.GetScreenshotsOfUser(job.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) != 0 && r.Storage == nil {
+		return 0, fmt.Errorf("storage is required to delete feedback screenshots")
+	}
+	for _, key := range keys {
+		if err := r.Storage.Delete(ctx, key); err != nil {
+			return 0, err
+		}
+	}
+
+	return r.UsersDB.FeedbackRepo.AnonymizeFeedbackOfUser(job.UserID)
+}
+
+func (r Runner) deleteRedisKey(keyOf func(userID string) string) func(context.Context, usersdb.AccountJob) (int64, error) {
+	return func(ctx context.Context, job usersdb.AccountJob) (int64, error) {
+		if r.RedisClient == nil {
+			return 0, fmt.Errorf("redis client is required")
+		}
+		return r.RedisClient.Del(ctx, keyOf(job.UserID.Hex())).Result()
+	}
+}
+
+// deleteUser also evicts the auth lookup of the user from the shared redis cache, which is the default cache of
+// services with redis, the step is retried if the eviction fails. In-memory caches (AUTH_USER_CACHE=memory) are
+// not reached, their entries are evicted when guards load roles of the user or expire after
+// auth.DefaultMemoryUserCacheTTL.
+func (r Runner) deleteUser(ctx context.Context, job usersdb.AccountJob) (int64, error) {
+	var deleted int64 = 1
+	_, err := r.UsersDB.UsersRepo.DeleteUserByID(job.UserID)
+	if err == mongo.ErrNoDocuments {
+		deleted = 0
+	} else if err != nil {
+		return 0, err
+	}
+	if r.RedisClient != nil {
+		if err := auth.NewRedisUserCache(r.RedisClient).Delete(ctx, job.AuthID); err != nil {
+			return 0, err
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,41 @@
+package account
+
+import (
+	"testing"
+
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/collectingdb"
+	"blinders/packages/db/practicedb"
+	"blinders/packages/db/usersdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeletionSteps(t *testing.T) {
+	r := NewRunner(
+		&usersdb.UsersDB{},
+		&chatdb.ChatDB{},
+		nil,
+		&collectingdb.CollectingDB{
+			ExplainLogsRepo:   &collectingdb.ExplainLogsRepo{},
+			TranslateLogsRepo: &collectingdb.TranslateLogsRepo{},
+		},
+		&practicedb.PracticeDB{},
+		nil,
+		nil,
+	)
+
+	steps, err := r.Steps(usersdb.AccountDeletionJob)
+	assert.Nil(t, err)
+
+	names := make(map[string]bool)
+	for _, step := range steps {
+		assert.False(t, names[step.Name], "duplicated step: %s", step.Name)
+		names[step.Name] = true
+	}
+	// the user is deleted at last so that the job is still related to the user on failures
+	assert.Equal(t, "user", steps[len(steps)-1].Name)
+
+	_, err = r.Steps("unknown")
+	assert.NotNil(t, err)
+}
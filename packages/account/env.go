@@ -0,0 +1,33 @@
+package account
+
+import (
+	"context"
+
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/collectingdb"
+	"blinders/packages/db/matchingdb"
+	"blinders/packages/db/practicedb"
+	"blinders/packages/db/usersdb"
+	dbutils "blinders/packages/db/utils"
+	"blinders/packages/storage"
+	"blinders/packages/utils"
+)
+
+// NewRunnerFromEnv inits the runner with the databases of USERS, CHAT, MATCHING, COLLECTING
+// and PRACTICE prefixes and the redis client from env
+func NewRunnerFromEnv(ctx context.Context, blobStorage storage.Storage) (*Runner, error) {
+	dbs, err := dbutils.InitMongoDatabasesFromEnv("USERS", "CHAT", "MATCHING", "COLLECTING", "PRACTICE")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRunner(
+		usersdb.NewUsersDB(dbs[0]),
+		chatdb.NewChatDB(dbs[1]),
+		matchingdb.NewMatchingRepo(dbs[2]),
+		collectingdb.NewCollectingDB(dbs[3]),
+		practicedb.NewPracticeDB(dbs[4]),
+		utils.NewRedisClientFromEnv(ctx),
+		blobStorage,
+	), nil
+}
@@ -0,0 +1,248 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/collectingdb"
+	"blinders/packages/db/practicedb"
+	"blinders/packages/db/usersdb"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportArchiveStep is the last step of export jobs, which zips the files of the other steps
+const ExportArchiveStep = "archive"
+
+// exportWriter writes the data of a file of the export, the number of written documents is returned
+type exportWriter func(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error)
+
+// ExportSteps collect data of the user in every database. Each step stores a JSON file of the data in the storage,
+// so the archive step zips them at last without querying again if the job is resumed.
+func (r Runner) ExportSteps() []Step {
+	files := []struct {
+		name  string
+		write exportWriter
+	}{
+		{"user", r.exportUser},
+		{"matching-profile", r.exportMatchingProfile},
+		{"friends", r.exportFriends},
+		{"conversations", func(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+			return exportCollection[chatdb.Conversation](ctx, r.ChatDB.ConversationsRepo.Collection,
+				bson.M{"members.userId": job.UserID}, w)
+		}},
+		{"messages", func(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+			return exportCollection[chatdb.Message](ctx, r.ChatDB.MessagesRepo.Collection,
+				bson.M{"senderId": job.UserID}, w)
+		}},
+		{"explain-logs", func(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+			return exportCollection[collectingdb.ExplainLog](ctx, r.CollectingDB.ExplainLogsRepo.Collection,
+				bson.M{"userId": job.UserID}, w)
+		}},
+		{"translate-logs", func(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+			return exportCollection[collectingdb.TranslateLog](ctx, r.CollectingDB.TranslateLogsRepo.Collection,
+				bson.M{"userId": job.UserID}, w)
+		}},
+		{"flashcards", func(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+			return exportCollection[practicedb.FlashcardCollection](ctx, r.PracticeDB.FlashcardsRepo.Collection,
+				bson.M{"userId": job.UserID}, w)
+		}},
+		{"feedback", func(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+			return exportCollection[exportedFeedback](ctx, r.UsersDB.FeedbackRepo.Collection,
+				bson.M{"userID": job.UserID}, w)
+		}},
+	}
+
+	steps := make([]Step, 0, len(files)+1)
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		steps = append(steps, Step{Name: file.name, Run: r.exportFile(file.name, file.write)})
+		names = append(names, file.name)
+	}
+	steps = append(steps, Step{
+		Name: ExportArchiveStep,
+		Run: func(ctx context.Context, job usersdb.AccountJob) (int64, error) {
+			return r.archiveExport(ctx, job, names)
+		},
+	})
+
+	return steps
+}
+
+func exportFileKey(job usersdb.AccountJob, name string) string {
+	return fmt.Sprintf("exports/%s/%s/%s.json", job.UserID.Hex(), job.ID.Hex(), name)
+}
+
+func exportArchiveKey(job usersdb.AccountJob) string {
+	return fmt.Sprintf("exports/%s/takeout-%s.zip", job.UserID.Hex(), job.ID.Hex())
+}
+
+// exportFile streams the data written by write to the file of the name in the storage
+func (r Runner) exportFile(name string, write exportWriter) func(context.Context, usersdb.AccountJob) (int64, error) {
+	return func(ctx context.Context, job usersdb.AccountJob) (int64, error) {
+		if r.Storage == nil {
+			return 0, fmt.Errorf("storage is required to export data")
+		}
+
+		reader, writer := io.Pipe()
+		written := make(chan int64, 1)
+		go func() {
+			n, err := write(ctx, job, writer)
+			written <- n
+			_ = writer.CloseWithError(err)
+		}()
+
+		_, err := r.Storage.Put(ctx, exportFileKey(job, name), "application/json", reader)
+		// unblock the writer if the storage stops reading on errors
+		_ = reader.CloseWithError(err)
+		n := <-written
+
+		return n, err
+	}
+}
+
+// archiveExport zips the files of the export into the archive and sets its key as the result of the job,
+// the files are removed after that. The step fails if the files could not be removed, so they are removed
+// again when the job is resumed.
+func (r Runner) archiveExport(ctx context.Context, job usersdb.AccountJob, names []string) (int64, error) {
+	if r.Storage == nil {
+		return 0, fmt.Errorf("storage is required to export data")
+	}
+
+	// the archive is already stored if the job was interrupted while removing the files
+	if job.Result == "" {
+		reader, writer := io.Pipe()
+		go func() {
+			_ = writer.CloseWithError(r.zipExportFiles(ctx, job, names, writer))
+		}()
+
+		_, err := r.Storage.Put(ctx, exportArchiveKey(job), "application/zip", reader)
+		_ = reader.CloseWithError(err)
+		if err != nil {
+			return 0, err
+		}
+		if err := r.UsersDB.AccountJobsRepo.SetAccountJobResult(job.ID, exportArchiveKey(job)); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, name := range names {
+		if err := r.Storage.Delete(ctx, exportFileKey(job, name)); err != nil {
+			return 0, fmt.Errorf("can not remove export file %s: %v", name, err)
+		}
+	}
+
+	return int64(len(names)), nil
+}
+
+func (r Runner) zipExportFiles(ctx context.Context, job usersdb.AccountJob, names []string, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	for _, name := range names {
+		file, err := r.Storage.Open(ctx, exportFileKey(job, name))
+		if err != nil {
+			return fmt.Errorf("can not open export file %s: %v", name, err)
+		}
+
+		entry, err := archive.Create(name + ".json")
+		if err == nil {
+			_, err = io.Copy(entry, file)
+		}
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("can not archive export file %s: %v", name, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+func (r Runner) exportUser(_ context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+	user, err := r.UsersDB.UsersRepo.GetUserByID(job.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	return 1, writeJSON(w, user)
+}
+
+func (r Runner) exportMatchingProfile(_ context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+	info, err := r.MatchingRepo.GetByUserID(job.UserID)
+	if err == mongo.ErrNoDocuments {
+		return 0, writeJSON(w, nil)
+	} else if err != nil {
+		return 0, err
+	}
+
+	return 1, writeJSON(w, info)
+}
+
+// exportFriends exports the public projection of friends of the user
+func (r Runner) exportFriends(ctx context.Context, job usersdb.AccountJob, w io.Writer) (int64, error) {
+	user, err := r.UsersDB.UsersRepo.GetUserByID(job.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	return exportCollection[usersdb.PublicUser](ctx, r.UsersDB.UsersRepo.Collection,
+		bson.M{"_id": bson.M{"$in": append([]primitive.ObjectID{}, user.FriendIDs...)}}, w)
+}
+
+// exportedFeedback is feedback without the internal fields of moderators
+type exportedFeedback struct {
+	usersdb.Feedback `bson:",inline"`
+}
+
+func (f exportedFeedback) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Public())
+}
+
+// exportCollection streams the documents matching the filter as a JSON array, documents are decoded as T
+// to be encoded with their JSON fields
+func exportCollection[T any](ctx context.Context, col *mongo.Collection, filter bson.M, w io.Writer) (int64, error) {
+	cursor, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	if _, err := io.WriteString(w, "[\n"); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return n, err
+		}
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return n, err
+		}
+		if n > 0 {
+			data = append([]byte(","), data...)
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := cursor.Err(); err != nil {
+		return n, err
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return n, err
+}
+
+func writeJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,57 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExportFiles(t *testing.T) {
+	s, err := storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/storage")
+	assert.Nil(t, err)
+	r := Runner{Storage: s}
+	job := usersdb.AccountJob{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID()}
+
+	n, err := r.exportFile("user", func(_ context.Context, _ usersdb.AccountJob, w io.Writer) (int64, error) {
+		return 1, writeJSON(w, map[string]string{"name": "user"})
+	})(context.Background(), job)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, err = r.exportFile("friends", func(_ context.Context, _ usersdb.AccountJob, w io.Writer) (int64, error) {
+		return 0, fmt.Errorf("can not get friends")
+	})(context.Background(), job)
+	assert.NotNil(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	assert.NotNil(t, r.zipExportFiles(context.Background(), job, []string{"user", "friends"}, buf))
+
+	buf.Reset()
+	assert.Nil(t, r.zipExportFiles(context.Background(), job, []string{"user"}, buf))
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(archive.File))
+	assert.Equal(t, "user.json", archive.File[0].Name)
+	file, err := archive.File[0].Open()
+	assert.Nil(t, err)
+	content, _ := io.ReadAll(file)
+	assert.JSONEq(t, `{"name": "user"}`, string(content))
+}
+
+func TestExportSteps(t *testing.T) {
+	steps, err := Runner{}.Steps(usersdb.AccountExportJob)
+	assert.Nil(t, err)
+	assert.Equal(t, ExportArchiveStep, steps[len(steps)-1].Name)
+
+	_, err = steps[0].Run(context.Background(), usersdb.AccountJob{})
+	assert.NotNil(t, err, "storage is required")
+}
@@ -0,0 +1,124 @@
+// Package account runs long running jobs on all data of a user across databases, e.g. account deletion
+package account
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/collectingdb"
+	"blinders/packages/db/matchingdb"
+	"blinders/packages/db/practicedb"
+	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Step is a unit of an account job, the number of processed documents or keys is returned.
+// Steps must be idempotent since a step interrupted before being recorded is run again on resume.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context, job usersdb.AccountJob) (int64, error)
+}
+
+type Runner struct {
+	UsersDB      *usersdb.UsersDB
+	ChatDB       *chatdb.ChatDB
+	MatchingRepo *matchingdb.MatchingRepo
+	CollectingDB *collectingdb.CollectingDB
+	PracticeDB   *practicedb.PracticeDB
+	RedisClient  *redis.Client
+	// Storage is the private storage of files and archives of export jobs and feedback screenshots, it must
+	// not be served publicly. Export jobs, and deletion of users with screenshots, fail if it is nil.
+	Storage storage.Storage
+}
+
+func NewRunner(
+	usersDB *usersdb.UsersDB,
+	chatDB *chatdb.ChatDB,
+	matchingRepo *matchingdb.MatchingRepo,
+	collectingDB *collectingdb.CollectingDB,
+	practiceDB *practicedb.PracticeDB,
+	redisClient *redis.Client,
+	blobStorage storage.Storage,
+) *Runner {
+	return &Runner{
+		UsersDB:      usersDB,
+		ChatDB:       chatDB,
+		MatchingRepo: matchingRepo,
+		CollectingDB: collectingDB,
+		PracticeDB:   practiceDB,
+		RedisClient:  redisClient,
+		Storage:      blobStorage,
+	}
+}
+
+// Steps returns the ordered steps of the job type
+func (r Runner) Steps(jobType usersdb.AccountJobType) ([]Step, error) {
+	switch jobType {
+	case usersdb.AccountDeletionJob:
+		return r.DeletionSteps(), nil
+	case usersdb.AccountExportJob:
+		return r.ExportSteps(), nil
+	default:
+		return nil, fmt.Errorf("unsupported account job type: %s", jobType)
+	}
+}
+
+// Run claims the job and runs its steps which are not completed yet, each completed step is recorded
+// so the job could be resumed by running it again if it fails or is interrupted
+func (r Runner) Run(ctx context.Context, jobID primitive.ObjectID) (*usersdb.AccountJob, error) {
+	repo := r.UsersDB.AccountJobsRepo
+	job, err := repo.ClaimAccountJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.runSteps(ctx, job)
+	if err != nil {
+		log.Printf("account job %s failed: %v\n", job.ID.Hex(), err)
+	}
+	if err := repo.FinishAccountJob(job.ID, err); err != nil {
+		return nil, err
+	}
+
+	finished, ferr := repo.GetAccountJobByID(job.ID)
+	if ferr != nil {
+		return nil, ferr
+	}
+	return finished, err
+}
+
+func (r Runner) runSteps(ctx context.Context, job *usersdb.AccountJob) error {
+	steps, err := r.Steps(job.Type)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		if job.CompletedStep(step.Name) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("step %s: %v", step.Name, err)
+		}
+
+		affected, err := step.Run(ctx, *job)
+		if err != nil {
+			return fmt.Errorf("step %s: %v", step.Name, err)
+		}
+		err = r.UsersDB.AccountJobsRepo.CompleteAccountJobStep(job.ID, usersdb.AccountJobStep{
+			Name:     step.Name,
+			Affected: affected,
+		})
+		if err != nil {
+			return fmt.Errorf("step %s: %v", step.Name, err)
+		}
+		log.Printf("account job %s: step %s completed, %d affected\n", job.ID.Hex(), step.Name, affected)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package chatdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type BotResponsesRepo struct {
+	*mongo.Collection
+}
+
+func NewBotResponsesRepo(db *mongo.Database) *BotResponsesRepo {
+	col := db.Collection(BotResponsesCollection)
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "botId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	if err != nil {
+		log.Println("can not create index for bot responses:", err)
+		return nil
+	}
+
+	return &BotResponsesRepo{col}
+}
+
+// this function creates new ID and time and insert the document to database
+func (r *BotResponsesRepo) InsertNewRawBotResponse(b BotResponse) (*BotResponse, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	b.ID = primitive.NewObjectID()
+	b.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	_, err := r.InsertOne(ctx, b)
+	if err != nil {
+		log.Println("can not insert bot response:", err)
+		return nil, fmt.Errorf("something went wrong when inserting bot response")
+	}
+
+	return &b, nil
+}
@@ -0,0 +1,40 @@
+package chatdb
+
+import "strings"
+
+type CommandType string
+
+const (
+	TranslateCommand CommandType = "translate"
+	ExplainCommand   CommandType = "explain"
+	DefineCommand    CommandType = "define"
+)
+
+var supportedCommands = map[CommandType]bool{
+	TranslateCommand: true,
+	ExplainCommand:   true,
+	DefineCommand:    true,
+}
+
+type Command struct {
+	Type CommandType
+	Args string
+}
+
+// ParseCommand parses slash command from message content, e.g. "/translate hello".
+// Content which is not a supported command must be sent as a normal message. It is shared by the
+// websocket chat which executes commands and the rest api which rejects them from scheduled messages.
+func ParseCommand(content string) (*Command, bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "/") {
+		return nil, false
+	}
+
+	name, args, _ := strings.Cut(content[1:], " ")
+	cmdType := CommandType(strings.ToLower(name))
+	if !supportedCommands[cmdType] {
+		return nil, false
+	}
+
+	return &Command{Type: cmdType, Args: strings.TrimSpace(args)}, true
+}
@@ -0,0 +1,33 @@
+package chatdb_test
+
+import (
+	"testing"
+
+	"blinders/packages/db/chatdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCommand(t *testing.T) {
+	cmd, ok := chatdb.ParseCommand("/translate vi-en xin chào")
+	assert.True(t, ok)
+	assert.Equal(t, chatdb.TranslateCommand, cmd.Type)
+	assert.Equal(t, "vi-en xin chào", cmd.Args)
+
+	cmd, ok = chatdb.ParseCommand("  /Define  serendipity ")
+	assert.True(t, ok)
+	assert.Equal(t, chatdb.DefineCommand, cmd.Type)
+	assert.Equal(t, "serendipity", cmd.Args)
+
+	cmd, ok = chatdb.ParseCommand("/explain")
+	assert.True(t, ok)
+	assert.Equal(t, chatdb.ExplainCommand, cmd.Type)
+	assert.Equal(t, "", cmd.Args)
+}
+
+func TestParseCommandFallbackToMessage(t *testing.T) {
+	for _, content := range []string{"hello world", "/unknown command", "a /translate b", "/"} {
+		_, ok := chatdb.ParseCommand(content)
+		assert.False(t, ok, content)
+	}
+}
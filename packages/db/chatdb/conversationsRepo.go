@@ -145,3 +145,214 @@ func (r *ConversationsRepo) InsertIndividualConversation(
 
 	return conv, err
 }
+
+// UpsertIndividualConversationWithContext returns the individual conversation of the users,
+// the conversation is created if it does not exist or reopened if it is read only.
+// The context could be a transaction context.
+func (r *ConversationsRepo) UpsertIndividualConversationWithContext(
+	ctx context.Context,
+	userID, friendID primitive.ObjectID,
+) (*Conversation, error) {
+	upsert := true
+	returnDocument := options.After
+	now := primitive.NewDateTimeFromTime(time.Now())
+	var conversation Conversation
+	err := r.FindOneAndUpdate(ctx,
+		bson.M{
+			"type": IndividualConversation,
+			"members": bson.M{
+				"$all": []bson.M{
+					{"$elemMatch": bson.M{"userId": userID}},
+					{"$elemMatch": bson.M{"userId": friendID}},
+				},
+				"$size": 2,
+			},
+		},
+		bson.M{
+			"$set": bson.M{"readOnly": false},
+			"$setOnInsert": Conversation{
+				ID:   primitive.NewObjectID(),
+				Type: IndividualConversation,
+				Members: []Member{{
+					UserID:    userID,
+					CreatedAt: now,
+					UpdatedAt: now,
+					JoinedAt:  now,
+				}, {
+					UserID:    friendID,
+					CreatedAt: now,
+					UpdatedAt: now,
+					JoinedAt:  now,
+				}},
+				CreatedBy: userID,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		},
+		&options.FindOneAndUpdateOptions{Upsert: &upsert, ReturnDocument: &returnDocument},
+	).Decode(&conversation)
+	if err != nil {
+		log.Println("can not upsert conversation:", err)
+		return nil, fmt.Errorf("something went wrong when upserting conversation")
+	}
+
+	return &conversation, nil
+}
+
+func (r *ConversationsRepo) UpdateMemberMuted(
+	conversationID primitive.ObjectID,
+	userID primitive.ObjectID,
+	muted bool,
+) error {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	result, err := r.UpdateOne(ctx,
+		bson.M{"_id": conversationID, "members.userId": userID},
+		bson.M{"$set": bson.M{
+			"members.$.muted":     muted,
+			"members.$.updatedAt": primitive.NewDateTimeFromTime(time.Now()),
+		}},
+	)
+	if err != nil {
+		log.Println("can not update member muted:", err)
+		return fmt.Errorf("something went wrong when updating conversation")
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user is not a member of conversation")
+	}
+
+	return nil
+}
+
+// PinMessage pins the message to the conversation, the number of pinned messages is bounded by MaxPinnedMessages
+func (r *ConversationsRepo) PinMessage(
+	conversationID primitive.ObjectID,
+	messageID primitive.ObjectID,
+	userID primitive.ObjectID,
+) (*Conversation, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	returnDocument := options.After
+	var conversation Conversation
+	err := r.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":            conversationID,
+			"members.userId": userID,
+			"pins.messageId": bson.M{"$ne": messageID},
+			fmt.Sprintf("pins.%d", MaxPinnedMessages-1): bson.M{"$exists": false},
+		},
+		bson.M{
+			"$push": bson.M{"pins": PinnedMessage{
+				MessageID: messageID,
+				PinnedBy:  userID,
+				PinnedAt:  now,
+			}},
+			"$set": bson.M{"updatedAt": now},
+		},
+		&options.FindOneAndUpdateOptions{ReturnDocument: &returnDocument},
+	).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf(
+			"can not pin message, it is already pinned or the conversation reaches %d pinned messages",
+			MaxPinnedMessages,
+		)
+	} else if err != nil {
+		log.Println("can not pin message:", err)
+		return nil, fmt.Errorf("something went wrong when pinning message")
+	}
+
+	return &conversation, nil
+}
+
+func (r *ConversationsRepo) UnpinMessage(
+	conversationID primitive.ObjectID,
+	messageID primitive.ObjectID,
+	userID primitive.ObjectID,
+) (*Conversation, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	returnDocument := options.After
+	var conversation Conversation
+	err := r.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":            conversationID,
+			"members.userId": userID,
+			"pins.messageId": messageID,
+		},
+		bson.M{
+			"$pull": bson.M{"pins": bson.M{"messageId": messageID}},
+			"$set":  bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+		},
+		&options.FindOneAndUpdateOptions{ReturnDocument: &returnDocument},
+	).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("message is not pinned in conversation")
+	} else if err != nil {
+		log.Println("can not unpin message:", err)
+		return nil, fmt.Errorf("something went wrong when unpinning message")
+	}
+
+	return &conversation, nil
+}
+
+// UpdateIndividualConversationReadOnly updates the read only state of the individual conversation of the users
+// if it exists, it returns nil if the users do not have a conversation
+func (r *ConversationsRepo) UpdateIndividualConversationReadOnly(
+	userID, friendID primitive.ObjectID,
+	readOnly bool,
+) error {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	_, err := r.UpdateOne(ctx,
+		bson.M{
+			"type": IndividualConversation,
+			"members": bson.M{
+				"$all": []bson.M{
+					{"$elemMatch": bson.M{"userId": userID}},
+					{"$elemMatch": bson.M{"userId": friendID}},
+				},
+				"$size": 2,
+			},
+		},
+		bson.M{"$set": bson.M{
+			"readOnly":  readOnly,
+			"updatedAt": primitive.NewDateTimeFromTime(time.Now()),
+		}},
+	)
+	if err != nil {
+		log.Println("can not update conversation:", err)
+		return fmt.Errorf("something went wrong when updating conversation")
+	}
+
+	return nil
+}
+
+// RemoveMemberFromConversations removes the user from members of their conversations,
+// individual conversations become read only since the other member is left alone
+func (r *ConversationsRepo) RemoveMemberFromConversations(userID primitive.ObjectID) (int64, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second*30)
+	defer cal()
+
+	pull := bson.M{"members": bson.M{"userId": userID}}
+	individual, err := r.UpdateMany(ctx,
+		bson.M{"type": IndividualConversation, "members.userId": userID},
+		bson.M{"$pull": pull, "$set": bson.M{"readOnly": true}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	group, err := r.UpdateMany(ctx,
+		bson.M{"type": bson.M{"$ne": IndividualConversation}, "members.userId": userID},
+		bson.M{"$pull": pull},
+	)
+	if err != nil {
+		return individual.ModifiedCount, err
+	}
+
+	return individual.ModifiedCount + group.ModifiedCount, nil
+}
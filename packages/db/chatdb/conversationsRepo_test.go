@@ -1,6 +1,7 @@
 package chatdb_test
 
 import (
+	"context"
 	"testing"
 
 	"blinders/packages/db/chatdb"
@@ -64,3 +65,58 @@ func TestGetConversationWithAFriend(t *testing.T) {
 		assert.Equal(t, conv.ID, (*conversations)[0].ID)
 	}
 }
+
+func TestPinAndUnpinMessage(t *testing.T) {
+	userID, friendID := primitive.NewObjectID(), primitive.NewObjectID()
+	conv, _ := convRepo.InsertIndividualConversation(userID, friendID)
+	messageID := primitive.NewObjectID()
+
+	pinned, err := convRepo.PinMessage(conv.ID, messageID, userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pinned.Pins))
+	assert.Equal(t, messageID, pinned.Pins[0].MessageID)
+	assert.Equal(t, userID, pinned.Pins[0].PinnedBy)
+
+	_, err = convRepo.PinMessage(conv.ID, messageID, friendID)
+	assert.NotNil(t, err)
+
+	unpinned, err := convRepo.UnpinMessage(conv.ID, messageID, friendID)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(unpinned.Pins))
+
+	_, err = convRepo.UnpinMessage(conv.ID, messageID, friendID)
+	assert.NotNil(t, err)
+}
+
+func TestPinMessageFailedWithNonMemberOrFullPins(t *testing.T) {
+	userID, friendID := primitive.NewObjectID(), primitive.NewObjectID()
+	conv, _ := convRepo.InsertIndividualConversation(userID, friendID)
+
+	_, err := convRepo.PinMessage(conv.ID, primitive.NewObjectID(), primitive.NewObjectID())
+	assert.NotNil(t, err)
+
+	for i := 0; i < chatdb.MaxPinnedMessages; i++ {
+		_, err := convRepo.PinMessage(conv.ID, primitive.NewObjectID(), userID)
+		assert.Nil(t, err)
+	}
+	_, err = convRepo.PinMessage(conv.ID, primitive.NewObjectID(), userID)
+	assert.NotNil(t, err)
+}
+
+func TestUpsertIndividualConversationReopensReadOnlyConversation(t *testing.T) {
+	userID, friendID := primitive.NewObjectID(), primitive.NewObjectID()
+	ctx := context.Background()
+
+	conv, err := convRepo.UpsertIndividualConversationWithContext(ctx, userID, friendID)
+	assert.Nil(t, err)
+	assert.False(t, conv.ReadOnly)
+
+	assert.Nil(t, convRepo.UpdateIndividualConversationReadOnly(friendID, userID, true))
+	readOnly, _ := convRepo.GetConversationByID(conv.ID)
+	assert.True(t, readOnly.ReadOnly)
+
+	reopened, err := convRepo.UpsertIndividualConversationWithContext(ctx, friendID, userID)
+	assert.Nil(t, err)
+	assert.Equal(t, conv.ID, reopened.ID)
+	assert.False(t, reopened.ReadOnly)
+}
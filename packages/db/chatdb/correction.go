@@ -0,0 +1,57 @@
+package chatdb
+
+import "strings"
+
+// ComputeCorrectionDiff computes a word level diff from the original text to the corrected text.
+// Consecutive words with the same operation are merged into one segment.
+func ComputeCorrectionDiff(original string, corrected string) []DiffSegment {
+	from, to := strings.Fields(original), strings.Fields(corrected)
+
+	// lcs[i][j] is the length of the longest common subsequence of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := make([]DiffSegment, 0)
+	appendWord := func(op DiffOperation, word string) {
+		if last := len(diff) - 1; last >= 0 && diff[last].Operation == op {
+			diff[last].Text += " " + word
+			return
+		}
+		diff = append(diff, DiffSegment{Operation: op, Text: word})
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			appendWord(DiffEqual, from[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			appendWord(DiffDelete, from[i])
+			i++
+		default:
+			appendWord(DiffInsert, to[j])
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		appendWord(DiffDelete, from[i])
+	}
+	for ; j < len(to); j++ {
+		appendWord(DiffInsert, to[j])
+	}
+
+	return diff
+}
@@ -0,0 +1,26 @@
+package chatdb_test
+
+import (
+	"testing"
+
+	"blinders/packages/db/chatdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeCorrectionDiff(t *testing.T) {
+	diff := chatdb.ComputeCorrectionDiff("I goes to school yesterday", "I went to school yesterday")
+	assert.Equal(t, []chatdb.DiffSegment{
+		{Operation: chatdb.DiffEqual, Text: "I"},
+		{Operation: chatdb.DiffDelete, Text: "goes"},
+		{Operation: chatdb.DiffInsert, Text: "went"},
+		{Operation: chatdb.DiffEqual, Text: "to school yesterday"},
+	}, diff)
+}
+
+func TestComputeCorrectionDiffWithEmptyText(t *testing.T) {
+	assert.Equal(t, []chatdb.DiffSegment{
+		{Operation: chatdb.DiffInsert, Text: "hello world"},
+	}, chatdb.ComputeCorrectionDiff("", "hello world"))
+	assert.Equal(t, []chatdb.DiffSegment{}, chatdb.ComputeCorrectionDiff("", ""))
+}
@@ -3,20 +3,26 @@ package chatdb
 import "go.mongodb.org/mongo-driver/mongo"
 
 var (
-	ConversationsCollection = "conversations"
-	MessagesCollection      = "messages"
+	ConversationsCollection     = "conversations"
+	MessagesCollection          = "messages"
+	ScheduledMessagesCollection = "scheduled-messages"
+	BotResponsesCollection      = "bot-responses"
 )
 
 type ChatDB struct {
 	mongo.Database
-	ConversationsRepo *ConversationsRepo
-	MessagesRepo      *MessagesRepo
+	ConversationsRepo     *ConversationsRepo
+	MessagesRepo          *MessagesRepo
+	ScheduledMessagesRepo *ScheduledMessagesRepo
+	BotResponsesRepo      *BotResponsesRepo
 }
 
 func NewChatDB(db *mongo.Database) *ChatDB {
 	return &ChatDB{
-		Database:          *db,
-		ConversationsRepo: NewConversationsRepo(db),
-		MessagesRepo:      NewMessagesRepo(db),
+		Database:              *db,
+		ConversationsRepo:     NewConversationsRepo(db),
+		MessagesRepo:          NewMessagesRepo(db),
+		ScheduledMessagesRepo: NewScheduledMessagesRepo(db),
+		BotResponsesRepo:      NewBotResponsesRepo(db),
 	}
 }
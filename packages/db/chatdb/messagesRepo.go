@@ -16,7 +16,21 @@ type MessagesRepo struct {
 }
 
 func NewMessagesRepo(db *mongo.Database) *MessagesRepo {
-	return &MessagesRepo{db.Collection(MessagesCollection)}
+	col := db.Collection(MessagesCollection)
+	ctx, cal := context.WithTimeout(context.Background(), time.Second*5)
+	defer cal()
+
+	// replies of threads are queried by both branches of an $or, each branch needs an index
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "threadId", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "replyTo", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		// messages are still served without the indexes, only threads are slower
+		log.Println("can not create indexes for messages:", err)
+	}
+
+	return &MessagesRepo{col}
 }
 
 func (r MessagesRepo) ConstructNewMessage(
@@ -32,6 +46,7 @@ func (r MessagesRepo) ConstructNewMessage(
 	}
 	return Message{
 		ID:             primitive.NewObjectID(),
+		Type:           TextMessage,
 		Status:         "delivered",
 		Emotions:       make([]MessageEmotion, 0),
 		SenderID:       senderID,
@@ -94,3 +109,132 @@ func (r *MessagesRepo) GetMessagesOfConversation(
 
 	return &messages, nil
 }
+
+// GetMessagesOfConversationInTimeRange returns at most limit messages created in [from, to), the oldest first
+func (r *MessagesRepo) GetMessagesOfConversationInTimeRange(
+	conversationID primitive.ObjectID,
+	from time.Time,
+	to time.Time,
+	limit int64,
+) ([]Message, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	filter := bson.M{
+		"conversationId": conversationID,
+		"createdAt": bson.M{
+			"$gte": primitive.NewDateTimeFromTime(from),
+			"$lt":  primitive.NewDateTimeFromTime(to),
+		},
+	}
+	messages := make([]Message, 0)
+	cur, err := r.Find(ctx, filter,
+		&options.FindOptions{Sort: bson.M{"createdAt": 1}, Limit: &limit})
+	if err != nil {
+		log.Println("can not get messages:", err)
+		return nil, err
+	}
+	if err := cur.All(ctx, &messages); err != nil {
+		log.Println("can not parse messages:", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *MessagesRepo) GetMessagesByIDs(ids []primitive.ObjectID) ([]Message, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	messages := make([]Message, 0)
+	cur, err := r.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Println("can not get messages:", err)
+		return nil, err
+	}
+	if err := cur.All(ctx, &messages); err != nil {
+		log.Println("can not parse messages:", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// IncreaseReplyCount updates the counters of the thread root when a new reply is sent
+func (r *MessagesRepo) IncreaseReplyCount(rootID primitive.ObjectID, repliedAt primitive.DateTime) error {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	_, err := r.UpdateOne(ctx,
+		bson.M{"_id": rootID},
+		bson.M{
+			"$inc": bson.M{"replyCount": 1},
+			"$max": bson.M{"latestReplyAt": repliedAt},
+		},
+	)
+	if err != nil {
+		log.Println("can not update reply count:", err)
+	}
+
+	return err
+}
+
+// GetRepliesOfThread returns replies of the thread in sending order, the page starts after the given reply.
+// Replies sent before threads are introduced only have replyTo, they are included if they reply to the root.
+func (r *MessagesRepo) GetRepliesOfThread(
+	rootID primitive.ObjectID,
+	after *primitive.ObjectID,
+	limit int64,
+) ([]Message, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	filter := bson.M{"$or": []bson.M{
+		{"threadId": rootID},
+		{"replyTo": rootID, "threadId": bson.M{"$exists": false}},
+	}}
+	if after != nil {
+		filter["_id"] = bson.M{"$gt": *after}
+	}
+
+	messages := make([]Message, 0)
+	cur, err := r.Find(ctx, filter,
+		&options.FindOptions{Sort: bson.M{"_id": 1}, Limit: &limit})
+	if err != nil {
+		log.Println("can not get replies:", err)
+		return nil, err
+	}
+	if err := cur.All(ctx, &messages); err != nil {
+		log.Println("can not parse replies:", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// AnonymizeMessagesOfSender erases contents of messages sent by the user and removes emotions of the user,
+// messages are kept to preserve threads and conversations of other members
+func (r *MessagesRepo) AnonymizeMessagesOfSender(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.UpdateMany(ctx,
+		bson.M{"senderId": userID, "senderDeleted": bson.M{"$ne": true}},
+		bson.M{
+			"$set":   bson.M{"content": "", "senderDeleted": true},
+			"$unset": bson.M{"correction": "", "mentions": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	emotions, err := r.UpdateMany(ctx,
+		bson.M{"emotions.senderId": userID},
+		bson.M{"$pull": bson.M{"emotions": bson.M{"senderId": userID}}},
+	)
+	if err != nil {
+		return result.ModifiedCount, err
+	}
+
+	return result.ModifiedCount + emotions.ModifiedCount, nil
+}
@@ -0,0 +1,45 @@
+package chatdb_test
+
+import (
+	"testing"
+
+	"blinders/packages/db/chatdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var messagesRepo = chatdb.NewMessagesRepo(cclient.Database("blinders"))
+
+func TestGetRepliesOfThread(t *testing.T) {
+	senderID, conversationID := primitive.NewObjectID(), primitive.NewObjectID()
+	root, _ := messagesRepo.InsertNewMessage(
+		messagesRepo.ConstructNewMessage(senderID, conversationID, primitive.NilObjectID, "root"))
+
+	replies := make([]chatdb.Message, 0)
+	replyTo := root
+	for _, content := range []string{"first", "second", "third"} {
+		m := messagesRepo.ConstructNewMessage(senderID, conversationID, replyTo.ID, content)
+		threadID := replyTo.ThreadRootID()
+		m.ThreadID = &threadID
+		m, _ = messagesRepo.InsertNewMessage(m)
+		assert.Nil(t, messagesRepo.IncreaseReplyCount(threadID, m.CreatedAt))
+		replies = append(replies, m)
+		replyTo = m
+	}
+
+	updatedRoot, err := messagesRepo.GetMessageByID(root.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, updatedRoot.ReplyCount)
+	assert.Equal(t, replies[2].CreatedAt, *updatedRoot.LatestReplyAt)
+
+	page, err := messagesRepo.GetRepliesOfThread(root.ID, nil, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(page))
+	assert.Equal(t, replies[0].ID, page[0].ID)
+
+	page, err = messagesRepo.GetRepliesOfThread(root.ID, &page[1].ID, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(page))
+	assert.Equal(t, replies[2].ID, page[0].ID)
+}
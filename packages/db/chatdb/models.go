@@ -9,6 +9,8 @@ const (
 	GroupConversation      ConversationType = "group"
 )
 
+// ReadOnly conversations keep their history but do not accept new messages,
+// e.g. the individual conversation of users who are not friends anymore
 type Conversation struct {
 	ID        primitive.ObjectID    `bson:"_id"                json:"id"`
 	Type      ConversationType      `bson:"type"               json:"type"`
@@ -17,6 +19,17 @@ type Conversation struct {
 	CreatedAt primitive.DateTime    `bson:"createdAt"          json:"createdAt"`
 	UpdatedAt primitive.DateTime    `bson:"updatedAt"          json:"updatedAt"`
 	Metadata  *ConversationMetadata `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Pins      []PinnedMessage       `bson:"pins,omitempty"     json:"pins,omitempty"`
+	ReadOnly  bool                  `bson:"readOnly,omitempty" json:"readOnly,omitempty"`
+}
+
+// MaxPinnedMessages is the maximum number of pinned messages of a conversation
+const MaxPinnedMessages = 50
+
+type PinnedMessage struct {
+	MessageID primitive.ObjectID `bson:"messageId" json:"messageId"`
+	PinnedBy  primitive.ObjectID `bson:"pinnedBy"  json:"pinnedBy"`
+	PinnedAt  primitive.DateTime `bson:"pinnedAt"  json:"pinnedAt"`
 }
 
 type ConversationMetadata struct {
@@ -24,10 +37,12 @@ type ConversationMetadata struct {
 	Image string `bson:"image,omitempty" json:"image,omitempty"`
 }
 
+// Muted members are not notified of new messages, except the messages mentioning them
 type Member struct {
 	UserID                primitive.ObjectID  `bson:"userId"                          json:"userId"`
 	Nickname              string              `bson:"nickname,omitempty"              json:"nickname,omitempty"`
 	LatestViewedMessageID *primitive.ObjectID `bson:"latestViewedMessageId,omitempty" json:"latestViewedMessageId,omitempty"`
+	Muted                 bool                `bson:"muted,omitempty"                 json:"muted,omitempty"`
 	CreatedAt             primitive.DateTime  `bson:"createdAt"                       json:"createdAt"`
 	UpdatedAt             primitive.DateTime  `bson:"updatedAt"                       json:"updatedAt"`
 	JoinedAt              primitive.DateTime  `bson:"joinedAt"                        json:"joinedAt"`
@@ -41,16 +56,68 @@ const (
 	SeenStatus      MessageStatus = "seen"
 )
 
+type MessageType string
+
+const (
+	// messages stored before message type is introduced do not have type,
+	// they are considered as text messages
+	TextMessage       MessageType = "text"
+	CorrectionMessage MessageType = "correction"
+)
+
+// Mentions of a message keeps the members mentioned in the content by "@name".
+// A reply belongs to the thread of the root message (ThreadID), which is the first message of the reply chain,
+// only the root message keeps the counters of its thread.
+// Contents of messages are erased if their sender deleted their account (SenderDeleted).
 type Message struct {
-	ID             primitive.ObjectID  `bson:"_id"               json:"id"`
-	SenderID       primitive.ObjectID  `bson:"senderId"          json:"senderId"`
-	ConversationID primitive.ObjectID  `bson:"conversationId"    json:"conversationId"`
-	ReplyTo        *primitive.ObjectID `bson:"replyTo,omitempty" json:"replyTo,omitempty"`
-	Content        string              `bson:"content"           json:"content"`
-	Status         MessageStatus       `bson:"status"            json:"status"`
-	CreatedAt      primitive.DateTime  `bson:"createdAt"         json:"createdAt"`
-	UpdatedAt      primitive.DateTime  `bson:"updatedAt"         json:"updatedAt"`
-	Emotions       []MessageEmotion    `bson:"emotions"          json:"emotions"`
+	ID             primitive.ObjectID   `bson:"_id"                     json:"id"`
+	Type           MessageType          `bson:"type,omitempty"          json:"type,omitempty"`
+	SenderID       primitive.ObjectID   `bson:"senderId"                json:"senderId"`
+	ConversationID primitive.ObjectID   `bson:"conversationId"          json:"conversationId"`
+	ReplyTo        *primitive.ObjectID  `bson:"replyTo,omitempty"       json:"replyTo,omitempty"`
+	Content        string               `bson:"content"                 json:"content"`
+	Status         MessageStatus        `bson:"status"                  json:"status"`
+	CreatedAt      primitive.DateTime   `bson:"createdAt"               json:"createdAt"`
+	UpdatedAt      primitive.DateTime   `bson:"updatedAt"               json:"updatedAt"`
+	Emotions       []MessageEmotion     `bson:"emotions"                json:"emotions"`
+	Correction     *MessageCorrection   `bson:"correction,omitempty"    json:"correction,omitempty"`
+	Mentions       []primitive.ObjectID `bson:"mentions,omitempty"      json:"mentions,omitempty"`
+	ThreadID       *primitive.ObjectID  `bson:"threadId,omitempty"      json:"threadId,omitempty"`
+	ReplyCount     int                  `bson:"replyCount"              json:"replyCount"`
+	LatestReplyAt  *primitive.DateTime  `bson:"latestReplyAt,omitempty" json:"latestReplyAt,omitempty"`
+	SenderDeleted  bool                 `bson:"senderDeleted,omitempty" json:"senderDeleted,omitempty"`
+}
+
+// ThreadRootID returns the root message of the thread which the message belongs to,
+// a message which is not a reply is the root of its own thread
+func (m Message) ThreadRootID() primitive.ObjectID {
+	if m.ThreadID != nil {
+		return *m.ThreadID
+	}
+	return m.ID
+}
+
+// MessageCorrection is the content of a correction message,
+// the corrected message is referenced by the ReplyTo field of the message
+type MessageCorrection struct {
+	// CorrectedUserID is the sender of the corrected message
+	CorrectedUserID primitive.ObjectID `bson:"correctedUserId" json:"correctedUserId"`
+	Original        string             `bson:"original"        json:"original"`
+	Corrected       string             `bson:"corrected"       json:"corrected"`
+	Diff            []DiffSegment      `bson:"diff"            json:"diff"`
+}
+
+type DiffOperation string
+
+const (
+	DiffEqual  DiffOperation = "equal"
+	DiffInsert DiffOperation = "insert"
+	DiffDelete DiffOperation = "delete"
+)
+
+type DiffSegment struct {
+	Operation DiffOperation `bson:"operation" json:"operation"`
+	Text      string        `bson:"text"      json:"text"`
 }
 
 type MessageEmotion struct {
@@ -59,3 +126,44 @@ type MessageEmotion struct {
 	CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
 	UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
 }
+
+type ScheduledMessageStatus string
+
+const (
+	PendingScheduledMessage     ScheduledMessageStatus = "pending"
+	DispatchingScheduledMessage ScheduledMessageStatus = "dispatching"
+	SentScheduledMessage        ScheduledMessageStatus = "sent"
+	CancelledScheduledMessage   ScheduledMessageStatus = "cancelled"
+	FailedScheduledMessage      ScheduledMessageStatus = "failed"
+)
+
+// ScheduledMessage is sent as a normal message to the conversation at ScheduledAt,
+// MessageID references the sent message.
+type ScheduledMessage struct {
+	ID             primitive.ObjectID     `bson:"_id"                 json:"id"`
+	SenderID       primitive.ObjectID     `bson:"senderId"            json:"senderId"`
+	ConversationID primitive.ObjectID     `bson:"conversationId"      json:"conversationId"`
+	ReplyTo        *primitive.ObjectID    `bson:"replyTo,omitempty"   json:"replyTo,omitempty"`
+	Content        string                 `bson:"content"             json:"content"`
+	ScheduledAt    primitive.DateTime     `bson:"scheduledAt"         json:"scheduledAt"`
+	Status         ScheduledMessageStatus `bson:"status"              json:"status"`
+	MessageID      *primitive.ObjectID    `bson:"messageId,omitempty" json:"messageId,omitempty"`
+	Error          string                 `bson:"error,omitempty"     json:"error,omitempty"`
+	CreatedAt      primitive.DateTime     `bson:"createdAt"           json:"createdAt"`
+	UpdatedAt      primitive.DateTime     `bson:"updatedAt"           json:"updatedAt"`
+}
+
+// BotResponse records how the bot responded to a message, MessageID references the reply of the bot
+// and is empty if the bot failed to respond. Latency is in milliseconds.
+type BotResponse struct {
+	ID             primitive.ObjectID  `bson:"_id"                 json:"id"`
+	BotID          primitive.ObjectID  `bson:"botId"               json:"botId"`
+	ConversationID primitive.ObjectID  `bson:"conversationId"      json:"conversationId"`
+	ReplyTo        primitive.ObjectID  `bson:"replyTo"             json:"replyTo"`
+	MessageID      *primitive.ObjectID `bson:"messageId,omitempty" json:"messageId,omitempty"`
+	Latency        int64               `bson:"latency"             json:"latency"`
+	RequestTokens  int                 `bson:"requestTokens"       json:"requestTokens"`
+	ResponseTokens int                 `bson:"responseTokens"      json:"responseTokens"`
+	Error          string              `bson:"error,omitempty"     json:"error,omitempty"`
+	CreatedAt      primitive.DateTime  `bson:"createdAt"           json:"createdAt"`
+}
@@ -0,0 +1,200 @@
+package chatdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DispatchingTimeout is the duration after that a dispatching message is considered as
+// abandoned by the dispatcher (e.g. it is crashed) and could be claimed again
+var DispatchingTimeout = time.Minute * 5
+
+type ScheduledMessagesRepo struct {
+	*mongo.Collection
+}
+
+func NewScheduledMessagesRepo(db *mongo.Database) *ScheduledMessagesRepo {
+	col := db.Collection(ScheduledMessagesCollection)
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "scheduledAt", Value: 1}}},
+		{Keys: bson.D{{Key: "senderId", Value: 1}, {Key: "scheduledAt", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("can not create indexes for scheduled messages:", err)
+		return nil
+	}
+
+	return &ScheduledMessagesRepo{col}
+}
+
+// this function creates new ID, time, pending status and insert the document to database
+func (r *ScheduledMessagesRepo) InsertNewRawScheduledMessage(
+	m ScheduledMessage,
+) (*ScheduledMessage, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	m.ID = primitive.NewObjectID()
+	m.Status = PendingScheduledMessage
+	now := primitive.NewDateTimeFromTime(time.Now())
+	m.CreatedAt = now
+	m.UpdatedAt = now
+
+	_, err := r.InsertOne(ctx, m)
+	if err != nil {
+		log.Println("can not insert scheduled message:", err)
+		return nil, fmt.Errorf("something went wrong when inserting scheduled message")
+	}
+
+	return &m, nil
+}
+
+// get by all statuses by default, the earliest scheduled first
+func (r *ScheduledMessagesRepo) GetScheduledMessagesOfSender(
+	senderID primitive.ObjectID,
+	statuses ...ScheduledMessageStatus,
+) ([]ScheduledMessage, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	filter := bson.M{"senderId": senderID}
+	if len(statuses) != 0 {
+		filter["status"] = bson.M{"$in": statuses}
+	}
+
+	messages := make([]ScheduledMessage, 0)
+	cur, err := r.Find(ctx, filter, options.Find().SetSort(bson.M{"scheduledAt": 1}))
+	if err != nil {
+		log.Println("can not get scheduled messages:", err)
+		return nil, err
+	}
+	if err := cur.All(ctx, &messages); err != nil {
+		log.Println("can not parse scheduled messages:", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// CancelScheduledMessage cancels a pending scheduled message of the sender
+func (r *ScheduledMessagesRepo) CancelScheduledMessage(
+	id primitive.ObjectID,
+	senderID primitive.ObjectID,
+) (*ScheduledMessage, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	returnDocument := options.After
+	var message ScheduledMessage
+	err := r.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "senderId": senderID, "status": PendingScheduledMessage},
+		bson.M{"$set": bson.M{
+			"status":    CancelledScheduledMessage,
+			"updatedAt": primitive.NewDateTimeFromTime(time.Now()),
+		}},
+		&options.FindOneAndUpdateOptions{ReturnDocument: &returnDocument},
+	).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("pending scheduled message not found")
+	} else if err != nil {
+		log.Println("can not cancel scheduled message:", err)
+		return nil, fmt.Errorf("something went wrong when cancelling scheduled message")
+	}
+
+	return &message, nil
+}
+
+// ClaimDueScheduledMessage marks the earliest due message as dispatching and returns it, so that
+// concurrent dispatchers do not claim the same message. Delivery is at least once: a message whose
+// dispatcher stops before MarkScheduledMessageSent is claimed again after DispatchingTimeout, and it
+// is sent again if it had been sent. It returns mongo.ErrNoDocuments if there is no due message.
+func (r *ScheduledMessagesRepo) ClaimDueScheduledMessage(now time.Time) (*ScheduledMessage, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	returnDocument := options.After
+	var message ScheduledMessage
+	err := r.FindOneAndUpdate(ctx,
+		bson.M{
+			"scheduledAt": bson.M{"$lte": primitive.NewDateTimeFromTime(now)},
+			"$or": []bson.M{
+				{"status": PendingScheduledMessage},
+				{
+					"status":    DispatchingScheduledMessage,
+					"updatedAt": bson.M{"$lt": primitive.NewDateTimeFromTime(now.Add(-DispatchingTimeout))},
+				},
+			},
+		},
+		bson.M{"$set": bson.M{
+			"status":    DispatchingScheduledMessage,
+			"updatedAt": primitive.NewDateTimeFromTime(now),
+		}},
+		&options.FindOneAndUpdateOptions{
+			Sort:           bson.M{"scheduledAt": 1},
+			ReturnDocument: &returnDocument,
+		},
+	).Decode(&message)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// MarkScheduledMessageSent marks the dispatching message as sent with the id of the sent message
+func (r *ScheduledMessagesRepo) MarkScheduledMessageSent(
+	id primitive.ObjectID,
+	messageID primitive.ObjectID,
+) error {
+	return r.updateDispatchingMessage(id, bson.M{
+		"status":    SentScheduledMessage,
+		"messageId": messageID,
+	})
+}
+
+func (r *ScheduledMessagesRepo) MarkScheduledMessageFailed(id primitive.ObjectID, reason string) error {
+	return r.updateDispatchingMessage(id, bson.M{
+		"status": FailedScheduledMessage,
+		"error":  reason,
+	})
+}
+
+func (r *ScheduledMessagesRepo) updateDispatchingMessage(id primitive.ObjectID, set bson.M) error {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	set["updatedAt"] = primitive.NewDateTimeFromTime(time.Now())
+	_, err := r.UpdateOne(ctx,
+		bson.M{"_id": id, "status": DispatchingScheduledMessage},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		log.Println("can not update scheduled message:", err)
+		return fmt.Errorf("something went wrong when updating scheduled message")
+	}
+
+	return nil
+}
+
+// DeleteScheduledMessagesOfSender deletes messages scheduled by the user in any status
+func (r *ScheduledMessagesRepo) DeleteScheduledMessagesOfSender(senderID primitive.ObjectID) (int64, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second*30)
+	defer cal()
+
+	result, err := r.DeleteMany(ctx, bson.M{"senderId": senderID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
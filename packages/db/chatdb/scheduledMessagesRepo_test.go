@@ -0,0 +1,60 @@
+package chatdb_test
+
+import (
+	"testing"
+	"time"
+
+	"blinders/packages/db/chatdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var scheduledRepo = chatdb.NewScheduledMessagesRepo(cclient.Database("blinders"))
+
+func TestScheduleAndCancelMessage(t *testing.T) {
+	senderID := primitive.NewObjectID()
+	scheduled, err := scheduledRepo.InsertNewRawScheduledMessage(chatdb.ScheduledMessage{
+		SenderID:       senderID,
+		ConversationID: primitive.NewObjectID(),
+		Content:        "good morning",
+		ScheduledAt:    primitive.NewDateTimeFromTime(time.Now().Add(time.Hour)),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, chatdb.PendingScheduledMessage, scheduled.Status)
+
+	messages, err := scheduledRepo.GetScheduledMessagesOfSender(senderID, chatdb.PendingScheduledMessage)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages))
+
+	_, err = scheduledRepo.CancelScheduledMessage(scheduled.ID, primitive.NewObjectID())
+	assert.NotNil(t, err)
+
+	cancelled, err := scheduledRepo.CancelScheduledMessage(scheduled.ID, senderID)
+	assert.Nil(t, err)
+	assert.Equal(t, chatdb.CancelledScheduledMessage, cancelled.Status)
+
+	_, err = scheduledRepo.CancelScheduledMessage(scheduled.ID, senderID)
+	assert.NotNil(t, err)
+}
+
+func TestClaimDueScheduledMessageOnlyOnce(t *testing.T) {
+	scheduledAt := time.Now().Add(-time.Hour * 24 * 365 * 10) // earlier than any other test messages
+	scheduled, _ := scheduledRepo.InsertNewRawScheduledMessage(chatdb.ScheduledMessage{
+		SenderID:       primitive.NewObjectID(),
+		ConversationID: primitive.NewObjectID(),
+		Content:        "due message",
+		ScheduledAt:    primitive.NewDateTimeFromTime(scheduledAt),
+	})
+
+	claimed, err := scheduledRepo.ClaimDueScheduledMessage(scheduledAt)
+	assert.Nil(t, err)
+	assert.Equal(t, scheduled.ID, claimed.ID)
+	assert.Equal(t, chatdb.DispatchingScheduledMessage, claimed.Status)
+
+	_, err = scheduledRepo.ClaimDueScheduledMessage(scheduledAt)
+	assert.NotNil(t, err)
+
+	messageID := primitive.NewObjectID()
+	assert.Nil(t, scheduledRepo.MarkScheduledMessageSent(claimed.ID, messageID))
+}
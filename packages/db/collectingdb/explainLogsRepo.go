@@ -163,3 +163,15 @@ func (r ExplainLogsRepo) GetNumberOfExplainLog(
 
 	return count[0].Number, nil
 }
+
+func (r ExplainLogsRepo) DeleteLogsOfUser(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
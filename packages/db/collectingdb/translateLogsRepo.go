@@ -49,3 +49,15 @@ func (r TranslateLogsRepo) GetLogWithSmallestGetCountByUserID(
 
 	return &tlog, nil
 }
+
+func (r TranslateLogsRepo) DeleteLogsOfUser(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
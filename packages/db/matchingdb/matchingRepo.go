@@ -178,3 +178,23 @@ func (r *MatchingRepo) UpdateByUserID(userID primitive.ObjectID, update *MatchIn
 
 	return update, nil
 }
+
+// UpdateNameByUserID updates the name denormalized from the user,
+// it returns mongo.ErrNoDocuments if the user does not have matching information
+func (r *MatchingRepo) UpdateNameByUserID(userID primitive.ObjectID, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	res, err := r.UpdateOne(ctx,
+		bson.M{"userId": userID},
+		bson.M{"$set": bson.M{"name": name, "updatedAt": primitive.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -326,3 +326,16 @@ func (r *FlashcardsRepo) DeleteFlashCard(collectionID primitive.ObjectID, cardID
 
 	return nil
 }
+
+// DeleteCollectionsOfUser deletes flashcard collections of the user with their flashcards
+func (r *FlashcardsRepo) DeleteCollectionsOfUser(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
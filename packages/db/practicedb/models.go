@@ -16,12 +16,16 @@ const (
 	ManualCollectionType         CollectionType = "ManualCollection"
 	FromExplainLogCollectionType CollectionType = "FromExplainLogCollection"
 	DefaultCollectionType        CollectionType = "DefaultCollection"
+	CorrectionCollectionType     CollectionType = "CorrectionCollection"
+	RecapCollectionType          CollectionType = "RecapCollection"
 
 	ExplainLogToFlashcardSnapshotType SnapshotType = "ExplainLogToFlashcardSnapshot"
 
 	ExplainLogToFlashcardType FlashcardType = "ExplainLogFlashcard"
 	ManualFlashcardType       FlashcardType = "ManualFlashcard"
 	DefaultFlashcardType      FlashcardType = "ManualFlashcard"
+	CorrectionFlashcardType   FlashcardType = "CorrectionFlashcard"
+	RecapFlashcardType        FlashcardType = "RecapFlashcard"
 )
 
 type FlashcardCollection struct {
@@ -48,6 +52,14 @@ type ExplainLogFlashcardMetadata struct {
 	ExplainLogID primitive.ObjectID `json:"explainLogId" bson:"explain_log_id"`
 }
 
+type CorrectionFlashcardMetadata struct {
+	MessageID primitive.ObjectID `json:"messageId" bson:"message_id"`
+}
+
+type RecapFlashcardMetadata struct {
+	ConversationID primitive.ObjectID `json:"conversationId" bson:"conversation_id"`
+}
+
 type PracticeSnapshot struct {
 	dbutils.RawModel `json:",inline" bson:",inline"`
 	Type             SnapshotType       `json:"type" bson:"type"`
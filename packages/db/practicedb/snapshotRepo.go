@@ -68,3 +68,15 @@ func (r SnapshotsRepo) UpdateSnapshot(updateSnapshot *PracticeSnapshot) (*Practi
 
 	return updateSnapshot, nil
 }
+
+func (r SnapshotsRepo) DeleteSnapshotsOfUser(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
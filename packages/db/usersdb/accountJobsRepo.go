@@ -0,0 +1,205 @@
+package usersdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccountJobLease is the duration after which a running job is considered interrupted
+// (e.g. the function running it timed out) and could be claimed again
+const AccountJobLease = time.Minute * 15
+
+var ErrAccountJobNotClaimable = errors.New("account job is completed or being run")
+
+type AccountJobsRepo struct {
+	*mongo.Collection
+}
+
+func NewAccountJobsRepo(db *mongo.Database) *AccountJobsRepo {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	col := db.Collection(AccountJobsCollection)
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "type", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		log.Println("can not create index for account jobs:", err)
+		return nil
+	}
+
+	return &AccountJobsRepo{col}
+}
+
+func (r *AccountJobsRepo) InsertNewRawAccountJob(user User, jobType AccountJobType) (*AccountJob, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	job := AccountJob{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		AuthID:    user.FirebaseUID,
+		Type:      jobType,
+		Status:    AccountJobPending,
+		Steps:     make([]AccountJobStep, 0),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := r.InsertOne(ctx, job); err != nil {
+		log.Println("can not insert account job:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+
+	return &job, nil
+}
+
+func (r *AccountJobsRepo) GetAccountJobByID(id primitive.ObjectID) (*AccountJob, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	var job AccountJob
+	err := r.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// GetLatestAccountJobOfUser returns the latest job of the type of the user,
+// it returns mongo.ErrNoDocuments if the user does not have any
+func (r *AccountJobsRepo) GetLatestAccountJobOfUser(
+	userID primitive.ObjectID,
+	jobType AccountJobType,
+) (*AccountJob, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	var job AccountJob
+	err := r.FindOne(ctx,
+		bson.M{"userId": userID, "type": jobType},
+		options.FindOne().SetSort(bson.M{"_id": -1}),
+	).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// GetOrInsertAccountJob returns the latest job of the type of the user which is not completed
+// so that it is resumed, or inserts a new one
+func (r *AccountJobsRepo) GetOrInsertAccountJob(user User, jobType AccountJobType) (*AccountJob, error) {
+	job, err := r.GetLatestAccountJobOfUser(user.ID, jobType)
+	if err == nil && job.Status != AccountJobCompleted {
+		return job, nil
+	} else if err != nil && err != mongo.ErrNoDocuments {
+		log.Println("can not get latest account job:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+
+	return r.InsertNewRawAccountJob(user, jobType)
+}
+
+// ClaimAccountJob marks the job as running to prevent it from being run concurrently, pending and failed jobs
+// are claimable, so are running jobs which are not updated for AccountJobLease
+func (r *AccountJobsRepo) ClaimAccountJob(id primitive.ObjectID) (*AccountJob, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	now := time.Now()
+	var job AccountJob
+	err := r.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "$or": []bson.M{
+			{"status": bson.M{"$in": []AccountJobStatus{AccountJobPending, AccountJobFailed}}},
+			{
+				"status":    AccountJobRunning,
+				"updatedAt": bson.M{"$lt": primitive.NewDateTimeFromTime(now.Add(-AccountJobLease))},
+			},
+		}},
+		bson.M{
+			"$set":   bson.M{"status": AccountJobRunning, "updatedAt": primitive.NewDateTimeFromTime(now)},
+			"$unset": bson.M{"error": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAccountJobNotClaimable
+	} else if err != nil {
+		log.Println("can not claim account job:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+
+	return &job, nil
+}
+
+// CompleteAccountJobStep records the completed step, which also renews the lease of the running job
+func (r *AccountJobsRepo) CompleteAccountJobStep(id primitive.ObjectID, step AccountJobStep) error {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	step.CompletedAt = primitive.NewDateTimeFromTime(time.Now())
+	_, err := r.UpdateOne(ctx,
+		bson.M{"_id": id, "steps.name": bson.M{"$ne": step.Name}},
+		bson.M{
+			"$push": bson.M{"steps": step},
+			"$set":  bson.M{"updatedAt": step.CompletedAt},
+		},
+	)
+	if err != nil {
+		log.Println("can not complete account job step:", err)
+		return fmt.Errorf("something went wrong")
+	}
+
+	return nil
+}
+
+// SetAccountJobResult sets the result of the job, e.g. storage key of the archive of export jobs
+func (r *AccountJobsRepo) SetAccountJobResult(id primitive.ObjectID, result string) error {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	_, err := r.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"result": result, "updatedAt": primitive.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		log.Println("can not set account job result:", err)
+		return fmt.Errorf("something went wrong")
+	}
+
+	return nil
+}
+
+// FinishAccountJob sets the status of the running job to completed, or failed with the error message
+func (r *AccountJobsRepo) FinishAccountJob(id primitive.ObjectID, jobErr error) error {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	set := bson.M{
+		"status":    AccountJobCompleted,
+		"updatedAt": primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if jobErr != nil {
+		set["status"] = AccountJobFailed
+		set["error"] = jobErr.Error()
+	}
+
+	_, err := r.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		log.Println("can not finish account job:", err)
+		return fmt.Errorf("something went wrong")
+	}
+
+	return nil
+}
@@ -2,28 +2,247 @@ package usersdb
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"slices"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrInvalidFeedbackTransition = fmt.Errorf("invalid feedback status transition")
+
 type FeedbackRepo struct {
 	*mongo.Collection
 }
 
 func NewFeedbackRepo(db *mongo.Database) *FeedbackRepo {
-	return &FeedbackRepo{db.Collection(FeedbackCollection)}
+	col := db.Collection(FeedbackCollection)
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	// feedback is listed by the latest first, filtered by status or assignee
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "assigneeId", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		log.Println("can not create indexes for feedback:", err)
+		return nil
+	}
+
+	return &FeedbackRepo{col}
 }
 
+// InsertNewFeedback inserts the feedback as new, fields of the workflow are reset. The ID is kept if it is set,
+// e.g. screenshots are stored under the ID before the feedback is inserted.
 func (r *FeedbackRepo) InsertNewFeedback(f Feedback) (*Feedback, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	f.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	now := primitive.NewDateTimeFromTime(time.Now())
+	if f.ID.IsZero() {
+		f.ID = primitive.NewObjectID()
+	}
+	f.Status = FeedbackNew
+	f.AssigneeID = nil
+	f.Notes = nil
+	f.CreatedAt = now
+	f.UpdatedAt = now
+	if f.Category == "" {
+		f.Category = FeedbackOther
+	}
 
 	_, err := r.InsertOne(ctx, f)
 	if err != nil {
 		return nil, err
 	}
+	f.normalize()
 	return &f, nil
 }
+
+func (r *FeedbackRepo) GetFeedbackByID(id primitive.ObjectID) (*Feedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	f := &Feedback{}
+	if err := r.FindOne(ctx, bson.M{"_id": id}).Decode(f); err != nil {
+		return nil, err
+	}
+	f.normalize()
+
+	return f, nil
+}
+
+// GetFeedback returns feedback matching the filter by the latest first,
+// after is the ID of the last feedback of the previous page
+func (r *FeedbackRepo) GetFeedback(
+	filter FeedbackFilter,
+	after *primitive.ObjectID,
+	limit int64,
+) ([]Feedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	query := bson.M{}
+	// feedback submitted before the status workflow has no status and category
+	if filter.Status == FeedbackNew {
+		query["status"] = bson.M{"$in": bson.A{FeedbackNew, nil}}
+	} else if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Category == FeedbackOther {
+		query["category"] = bson.M{"$in": bson.A{FeedbackOther, nil}}
+	} else if filter.Category != "" {
+		query["category"] = filter.Category
+	}
+	if filter.AssigneeID != nil {
+		query["assigneeId"] = *filter.AssigneeID
+	}
+	if filter.UserID != nil {
+		query["userID"] = *filter.UserID
+	}
+	if after != nil {
+		query["_id"] = bson.M{"$lt": *after}
+	}
+
+	cur, err := r.Find(ctx, query, options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit))
+	if err != nil {
+		log.Println("can not get feedback:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+
+	feedback := make([]Feedback, 0)
+	if err := cur.All(ctx, &feedback); err != nil {
+		log.Println("can not decode feedback:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+	for i := range feedback {
+		feedback[i].normalize()
+	}
+
+	return feedback, nil
+}
+
+// UpdateFeedbackTriage updates status, category and assignee of the feedback. The status must be
+// a transition of FeedbackStatusTransitions from the current status, which is checked atomically,
+// ErrInvalidFeedbackTransition is returned otherwise.
+func (r *FeedbackRepo) UpdateFeedbackTriage(id primitive.ObjectID, triage FeedbackTriage) (*Feedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+	set := bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())}
+	update := bson.M{"$set": set}
+	if triage.Status != nil {
+		var from bson.A
+		for status, transitions := range FeedbackStatusTransitions {
+			if slices.Contains(transitions, *triage.Status) {
+				from = append(from, status)
+				if status == FeedbackNew {
+					from = append(from, nil)
+				}
+			}
+		}
+		if len(from) == 0 {
+			return nil, ErrInvalidFeedbackTransition
+		}
+		filter["status"] = bson.M{"$in": from}
+		set["status"] = *triage.Status
+	}
+	if triage.Category != nil {
+		set["category"] = *triage.Category
+	}
+	if triage.UnsetAssignee {
+		set["assigneeId"] = nil
+	} else if triage.AssigneeID != nil {
+		set["assigneeId"] = *triage.AssigneeID
+	}
+
+	f := &Feedback{}
+	err := r.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(f)
+	if err == mongo.ErrNoDocuments && triage.Status != nil {
+		// distinguish between missing feedback and the status which could not be moved from
+		if _, err := r.GetFeedbackByID(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidFeedbackTransition
+	} else if err != nil {
+		return nil, err
+	}
+	f.normalize()
+
+	return f, nil
+}
+
+// AddFeedbackNote appends the internal note to the feedback
+func (r *FeedbackRepo) AddFeedbackNote(id primitive.ObjectID, note FeedbackNote) (*Feedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	note.CreatedAt = now
+	f := &Feedback{}
+	err := r.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$push": bson.M{"notes": note},
+			"$set":  bson.M{"updatedAt": now},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	f.normalize()
+
+	return f, nil
+}
+
+// GetScreenshotsOfUser returns keys of screenshots attached to feedback of the user
+func (r *FeedbackRepo) GetScreenshotsOfUser(userID primitive.ObjectID) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	cur, err := r.Find(ctx,
+		bson.M{"userID": userID, "screenshots.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"screenshots": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var feedback []Feedback
+	if err := cur.All(ctx, &feedback); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	for _, f := range feedback {
+		keys = append(keys, f.Screenshots...)
+	}
+	return keys, nil
+}
+
+// AnonymizeFeedbackOfUser removes the user and the screenshots from the feedback, comments are kept.
+// The screenshots must be deleted from the storage before, they could not be found after.
+func (r *FeedbackRepo) AnonymizeFeedbackOfUser(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.UpdateMany(ctx,
+		bson.M{"userID": userID},
+		bson.M{
+			"$set":   bson.M{"userID": primitive.NilObjectID},
+			"$unset": bson.M{"screenshots": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
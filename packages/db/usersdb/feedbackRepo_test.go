@@ -0,0 +1,81 @@
+package usersdb_test
+
+import (
+	"testing"
+
+	"blinders/packages/db/usersdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var feedbackRepo = usersdb.NewFeedbackRepo(uclient.Database("blinders"))
+
+func TestFeedbackStatusWorkflow(t *testing.T) {
+	feedback, err := feedbackRepo.InsertNewFeedback(usersdb.Feedback{
+		UserID:  primitive.NewObjectID(),
+		Comment: "comment",
+		// users can not set the internal fields
+		Status: usersdb.FeedbackResolved,
+		Notes:  []usersdb.FeedbackNote{{Content: "note"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, usersdb.FeedbackNew, feedback.Status)
+	assert.Equal(t, usersdb.FeedbackOther, feedback.Category)
+	assert.Empty(t, feedback.Notes)
+
+	triaged := usersdb.FeedbackTriaged
+	bug := usersdb.FeedbackBug
+	assigneeID := primitive.NewObjectID()
+	updated, err := feedbackRepo.UpdateFeedbackTriage(feedback.ID, usersdb.FeedbackTriage{
+		Status:     &triaged,
+		Category:   &bug,
+		AssigneeID: &assigneeID,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, triaged, updated.Status)
+	assert.Equal(t, bug, updated.Category)
+	assert.Equal(t, assigneeID, *updated.AssigneeID)
+
+	// triaged feedback could not be triaged again
+	_, err = feedbackRepo.UpdateFeedbackTriage(feedback.ID, usersdb.FeedbackTriage{Status: &triaged})
+	assert.Equal(t, usersdb.ErrInvalidFeedbackTransition, err)
+
+	updated, err = feedbackRepo.UpdateFeedbackTriage(feedback.ID, usersdb.FeedbackTriage{UnsetAssignee: true})
+	assert.Nil(t, err)
+	assert.Nil(t, updated.AssigneeID)
+
+	updated, err = feedbackRepo.AddFeedbackNote(feedback.ID, usersdb.FeedbackNote{
+		AuthorID: assigneeID,
+		Content:  "note",
+	})
+	assert.Nil(t, err)
+	assert.Len(t, updated.Notes, 1)
+	assert.Empty(t, updated.Public().Notes)
+}
+
+func TestGetFeedback(t *testing.T) {
+	userID := primitive.NewObjectID()
+	inserted := make([]*usersdb.Feedback, 3)
+	for i := range inserted {
+		f, err := feedbackRepo.InsertNewFeedback(usersdb.Feedback{UserID: userID, Comment: "comment"})
+		assert.Nil(t, err)
+		inserted[i] = f
+	}
+
+	page, err := feedbackRepo.GetFeedback(usersdb.FeedbackFilter{UserID: &userID}, nil, 2)
+	assert.Nil(t, err)
+	assert.Len(t, page, 2)
+	// the latest feedback comes first
+	assert.Equal(t, inserted[2].ID, page[0].ID)
+
+	page, err = feedbackRepo.GetFeedback(usersdb.FeedbackFilter{UserID: &userID}, &page[1].ID, 2)
+	assert.Nil(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, inserted[0].ID, page[0].ID)
+
+	page, err = feedbackRepo.GetFeedback(
+		usersdb.FeedbackFilter{UserID: &userID, Status: usersdb.FeedbackResolved}, nil, 2)
+	assert.Nil(t, err)
+	assert.Empty(t, page)
+}
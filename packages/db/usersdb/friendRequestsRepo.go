@@ -2,6 +2,7 @@ package usersdb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -16,10 +17,41 @@ type FriendRequestsRepo struct {
 	*mongo.Collection
 }
 
+var ErrFriendRequestExisted = errors.New("request already existed")
+
 func NewFriendRequestsRepo(db *mongo.Database) *FriendRequestsRepo {
-	// TODO: need to add index for `to` and `status` for optimization,
-	// mostly querying pending requests to someone
-	return &FriendRequestsRepo{db.Collection(FriendRequestsCollection)}
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	col := db.Collection(FriendRequestsCollection)
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// a user can have only one pending request to another user
+			Keys: bson.D{{Key: "from", Value: 1}, {Key: "to", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": FriendStatusPending}),
+		},
+		{
+			// users can have only one pending request between them, so that concurrent requests of both users
+			// could not be both pending. Requests created before the pair is introduced are not indexed.
+			Keys: bson.M{"pair": 1},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{
+					"status": FriendStatusPending,
+					"pair":   bson.M{"$exists": true},
+				}),
+		},
+		{Keys: bson.D{{Key: "to", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "from", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("can not create indexes of friend requests:", err)
+		return nil
+	}
+
+	return &FriendRequestsRepo{col}
 }
 
 func (r *FriendRequestsRepo) InsertNewRawFriendRequest(
@@ -30,23 +62,78 @@ func (r *FriendRequestsRepo) InsertNewRawFriendRequest(
 	defer cancel()
 
 	request.ID = primitive.NewObjectID()
+	request.Pair = FriendRequestPair(request.From, request.To)
 	now := primitive.NewDateTimeFromTime(time.Now())
 	request.CreatedAt = now
 	request.UpdatedAt = now
 
-	upsert := true
-	result, err := r.UpdateOne(ctx,
-		bson.M{"from": request.From, "to": request.To},
-		bson.M{"$setOnInsert": request},
-		&options.UpdateOptions{Upsert: &upsert})
-	if err != nil {
+	_, err := r.InsertOne(ctx, request)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrFriendRequestExisted
+	} else if err != nil {
 		log.Println("can not insert friend request:", err)
 		return nil, fmt.Errorf("something went wrong")
-	} else if result.UpsertedCount == 0 {
-		return nil, fmt.Errorf("request already existed")
 	}
 
-	return &request, err
+	return &request, nil
+}
+
+// FriendRequestPair returns the same key of requests between users in both directions
+func FriendRequestPair(user1ID primitive.ObjectID, user2ID primitive.ObjectID) string {
+	if user1ID.Hex() > user2ID.Hex() {
+		user1ID, user2ID = user2ID, user1ID
+	}
+	return user1ID.Hex() + ":" + user2ID.Hex()
+}
+
+// GetPendingFriendRequestBetween returns the pending request sent from a user to another,
+// it returns mongo.ErrNoDocuments if there is no such request
+func (r *FriendRequestsRepo) GetPendingFriendRequestBetween(
+	from primitive.ObjectID,
+	to primitive.ObjectID,
+) (*FriendRequest, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Second)
+	defer cancel()
+
+	var request FriendRequest
+	err := r.FindOne(ctx, bson.M{
+		"from":   from,
+		"to":     to,
+		"status": FriendStatusPending,
+	}).Decode(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// CountFriendRequestsSince counts requests sent from a user to another since the given time,
+// in any status
+func (r *FriendRequestsRepo) CountFriendRequestsSince(
+	from primitive.ObjectID,
+	to primitive.ObjectID,
+	since time.Time,
+) (int64, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Second)
+	defer cancel()
+
+	return r.CountDocuments(ctx, bson.M{
+		"from":      from,
+		"to":        to,
+		"createdAt": bson.M{"$gte": primitive.NewDateTimeFromTime(since)},
+	})
+}
+
+// CountPendingFriendRequestsByFrom counts pending requests sent from the user
+func (r *FriendRequestsRepo) CountPendingFriendRequestsByFrom(from primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Second)
+	defer cancel()
+
+	return r.CountDocuments(ctx, bson.M{"from": from, "status": FriendStatusPending})
 }
 
 func (r *FriendRequestsRepo) GetFriendRequestByFrom(
@@ -59,7 +146,7 @@ func (r *FriendRequestsRepo) GetFriendRequestByFrom(
 
 	var filter bson.M
 	switch status {
-	case FriendStatusPending, FriendStatusAccepted, FriendStatusDenied:
+	case FriendStatusPending, FriendStatusAccepted, FriendStatusDenied, FriendStatusCancelled:
 		filter = bson.M{"from": from, "status": status}
 	default:
 		filter = bson.M{"from": from}
@@ -89,7 +176,7 @@ func (r *FriendRequestsRepo) GetFriendRequestByTo(
 
 	var filter bson.M
 	switch status {
-	case FriendStatusPending, FriendStatusAccepted, FriendStatusDenied:
+	case FriendStatusPending, FriendStatusAccepted, FriendStatusDenied, FriendStatusCancelled:
 		filter = bson.M{"to": to, "status": status}
 	default:
 		filter = bson.M{"to": to}
@@ -136,10 +223,24 @@ func (r *FriendRequestsRepo) UpdateFriendRequestStatusByID(
 		context.Background(), time.Second)
 	defer cancel()
 
+	return r.UpdateFriendRequestStatusByIDWithContext(ctx, id, userID, status)
+}
+
+// UpdateFriendRequestStatusByIDWithContext updates the pending request sent to the user,
+// the context could be a transaction context
+func (r *FriendRequestsRepo) UpdateFriendRequestStatusByIDWithContext(
+	ctx context.Context,
+	id primitive.ObjectID,
+	userID primitive.ObjectID,
+	status FriendRequestStatus,
+) (*FriendRequest, error) {
 	result, err := r.UpdateOne(
 		ctx,
 		bson.M{"_id": id, "to": userID, "status": FriendStatusPending},
-		bson.M{"$set": bson.M{"status": status}},
+		bson.M{"$set": bson.M{
+			"status":    status,
+			"updatedAt": primitive.NewDateTimeFromTime(time.Now()),
+		}},
 	)
 	if err != nil {
 		log.Println("can not update friend request:", err)
@@ -157,3 +258,66 @@ func (r *FriendRequestsRepo) UpdateFriendRequestStatusByID(
 
 	return &request, nil
 }
+
+// CancelFriendRequest withdraws the pending request sent from the user
+func (r *FriendRequestsRepo) CancelFriendRequest(
+	id primitive.ObjectID,
+	from primitive.ObjectID,
+) (*FriendRequest, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Second)
+	defer cancel()
+
+	var request FriendRequest
+	err := r.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "from": from, "status": FriendStatusPending},
+		bson.M{"$set": bson.M{
+			"status":    FriendStatusCancelled,
+			"updatedAt": primitive.NewDateTimeFromTime(time.Now()),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&request)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("not found this friend request")
+	} else if err != nil {
+		log.Println("can not cancel friend request:", err)
+		return nil, fmt.Errorf("can not cancel friend request")
+	}
+
+	return &request, nil
+}
+
+// RestorePendingFriendRequest sets the responded request back to pending,
+// it compensates a failed response when transactions are not supported
+func (r *FriendRequestsRepo) RestorePendingFriendRequest(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": bson.M{"$ne": FriendStatusPending}},
+		bson.M{"$set": bson.M{"status": FriendStatusPending}},
+	)
+	if err != nil {
+		log.Println("can not restore friend request:", err)
+		return fmt.Errorf("can not restore friend request")
+	}
+
+	return nil
+}
+
+// DeleteFriendRequestsOfUser deletes requests sent from or to the user
+func (r *FriendRequestsRepo) DeleteFriendRequestsOfUser(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.DeleteMany(ctx, bson.M{"$or": []bson.M{{"from": userID}, {"to": userID}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
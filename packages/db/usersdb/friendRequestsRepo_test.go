@@ -0,0 +1,66 @@
+package usersdb_test
+
+import (
+	"testing"
+
+	"blinders/packages/db/usersdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var friendRequestsRepo = usersdb.NewFriendRequestsRepo(uclient.Database("blinders"))
+
+func TestInsertFriendRequestFailedWithDuplicatedPendingRequest(t *testing.T) {
+	request := usersdb.FriendRequest{
+		From:   primitive.NewObjectID(),
+		To:     primitive.NewObjectID(),
+		Status: usersdb.FriendStatusPending,
+	}
+	_, err := friendRequestsRepo.InsertNewRawFriendRequest(request)
+	assert.Nil(t, err)
+	_, err = friendRequestsRepo.InsertNewRawFriendRequest(request)
+	assert.Equal(t, usersdb.ErrFriendRequestExisted, err)
+}
+
+func TestInsertFriendRequestFailedWithReversePendingRequest(t *testing.T) {
+	request := usersdb.FriendRequest{
+		From:   primitive.NewObjectID(),
+		To:     primitive.NewObjectID(),
+		Status: usersdb.FriendStatusPending,
+	}
+	_, err := friendRequestsRepo.InsertNewRawFriendRequest(request)
+	assert.Nil(t, err)
+
+	request.From, request.To = request.To, request.From
+	_, err = friendRequestsRepo.InsertNewRawFriendRequest(request)
+	assert.Equal(t, usersdb.ErrFriendRequestExisted, err)
+}
+
+func TestCancelFriendRequest(t *testing.T) {
+	request, err := friendRequestsRepo.InsertNewRawFriendRequest(usersdb.FriendRequest{
+		From:   primitive.NewObjectID(),
+		To:     primitive.NewObjectID(),
+		Status: usersdb.FriendStatusPending,
+	})
+	assert.Nil(t, err)
+
+	// only the sender can cancel the request
+	_, err = friendRequestsRepo.CancelFriendRequest(request.ID, request.To)
+	assert.NotNil(t, err)
+
+	cancelled, err := friendRequestsRepo.CancelFriendRequest(request.ID, request.From)
+	assert.Nil(t, err)
+	assert.Equal(t, usersdb.FriendStatusCancelled, cancelled.Status)
+
+	_, err = friendRequestsRepo.CancelFriendRequest(request.ID, request.From)
+	assert.NotNil(t, err)
+
+	// a new request could be sent after the pending one is cancelled
+	_, err = friendRequestsRepo.InsertNewRawFriendRequest(usersdb.FriendRequest{
+		From:   request.From,
+		To:     request.To,
+		Status: usersdb.FriendStatusPending,
+	})
+	assert.Nil(t, err)
+}
@@ -6,6 +6,7 @@ var (
 	UsersCollection          = "users"
 	FriendRequestsCollection = "friend-requests"
 	FeedbackCollection       = "feedback"
+	AccountJobsCollection    = "account-jobs"
 )
 
 type UsersDB struct {
@@ -13,6 +14,7 @@ type UsersDB struct {
 	UsersRepo          *UsersRepo
 	FriendRequestsRepo *FriendRequestsRepo
 	FeedbackRepo       *FeedbackRepo
+	AccountJobsRepo    *AccountJobsRepo
 }
 
 func NewUsersDB(db *mongo.Database) *UsersDB {
@@ -21,5 +23,6 @@ func NewUsersDB(db *mongo.Database) *UsersDB {
 		UsersRepo:          NewUsersRepo(db),
 		FriendRequestsRepo: NewFriendRequestsRepo(db),
 		FeedbackRepo:       NewFeedbackRepo(db),
+		AccountJobsRepo:    NewAccountJobsRepo(db),
 	}
 }
@@ -1,19 +1,119 @@
 package usersdb
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
-	ID          primitive.ObjectID   `bson:"_id"         json:"id"`
-	Name        string               `bson:"name"        json:"name"`
-	Email       string               `bson:"email"       json:"email"`
-	FirebaseUID string               `bson:"firebaseUID" json:"firebaseUID"`
-	ImageURL    string               `bson:"imageURL"    json:"imageURL"`
-	FriendIDs   []primitive.ObjectID `bson:"friends"     json:"friends"`
-	CreatedAt   primitive.DateTime   `bson:"createdAt"   json:"createdAt"`
-	UpdatedAt   primitive.DateTime   `bson:"updatedAt"   json:"updatedAt"`
+	ID          primitive.ObjectID   `bson:"_id"               json:"id"`
+	Name        string               `bson:"name"              json:"name"`
+	Email       string               `bson:"email"             json:"email"`
+	FirebaseUID string               `bson:"firebaseUID"       json:"firebaseUID"`
+	ImageURL    string               `bson:"imageURL"          json:"imageURL"`
+	Bio         string               `bson:"bio,omitempty"     json:"bio,omitempty"`
+	FriendIDs   []primitive.ObjectID `bson:"friends"           json:"friends"`
+	BlockedIDs  []primitive.ObjectID `bson:"blocked,omitempty" json:"blocked,omitempty"`
+	Roles       []string             `bson:"roles,omitempty"   json:"roles,omitempty"`
+	IsBot       bool                 `bson:"isBot,omitempty"   json:"isBot,omitempty"`
+	SearchName  string               `bson:"searchName"        json:"-"`
+	SearchEmail string               `bson:"searchEmail"       json:"-"`
+	CreatedAt   primitive.DateTime   `bson:"createdAt"         json:"createdAt"`
+	UpdatedAt   primitive.DateTime   `bson:"updatedAt"         json:"updatedAt"`
 	// Conversations []EmbeddedConversation `bson:"conversations" json:"conversations"`
 }
 
+// PublicUser is the projection of a user which could be shown to other users
+type PublicUser struct {
+	ID       primitive.ObjectID `bson:"_id"             json:"id"`
+	Name     string             `bson:"name"            json:"name"`
+	ImageURL string             `bson:"imageURL"        json:"imageURL"`
+	Bio      string             `bson:"bio,omitempty"   json:"bio,omitempty"`
+	IsBot    bool               `bson:"isBot,omitempty" json:"isBot,omitempty"`
+}
+
+// SelfUser is the projection of a user which is shown to the user themself,
+// the firebaseUID is internal and never serialized
+type SelfUser struct {
+	ID         primitive.ObjectID   `bson:"_id"             json:"id"`
+	Name       string               `bson:"name"            json:"name"`
+	Email      string               `bson:"email"           json:"email"`
+	ImageURL   string               `bson:"imageURL"        json:"imageURL"`
+	Bio        string               `bson:"bio,omitempty"   json:"bio,omitempty"`
+	FriendIDs  []primitive.ObjectID `bson:"friends"         json:"friends"`
+	BlockedIDs []primitive.ObjectID `bson:"blocked"         json:"blocked"`
+	Roles      []string             `bson:"roles,omitempty" json:"roles,omitempty"`
+	IsBot      bool                 `bson:"isBot,omitempty" json:"isBot,omitempty"`
+	CreatedAt  primitive.DateTime   `bson:"createdAt"       json:"createdAt"`
+	UpdatedAt  primitive.DateTime   `bson:"updatedAt"       json:"updatedAt"`
+}
+
+func (u User) Public() PublicUser {
+	return PublicUser{
+		ID:       u.ID,
+		Name:     u.Name,
+		ImageURL: u.ImageURL,
+		Bio:      u.Bio,
+		IsBot:    u.IsBot,
+	}
+}
+
+func (u User) Self() SelfUser {
+	friendIDs, blockedIDs := u.FriendIDs, u.BlockedIDs
+	if friendIDs == nil {
+		friendIDs = make([]primitive.ObjectID, 0)
+	}
+	if blockedIDs == nil {
+		blockedIDs = make([]primitive.ObjectID, 0)
+	}
+
+	return SelfUser{
+		ID:         u.ID,
+		Name:       u.Name,
+		Email:      u.Email,
+		ImageURL:   u.ImageURL,
+		Bio:        u.Bio,
+		FriendIDs:  friendIDs,
+		BlockedIDs: blockedIDs,
+		Roles:      u.Roles,
+		IsBot:      u.IsBot,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+	}
+}
+
+// WithSearchFields returns the user with the lowercase name and email, which are indexed for prefix search
+func (u User) WithSearchFields() User {
+	u.SearchName = strings.ToLower(u.Name)
+	u.SearchEmail = strings.ToLower(u.Email)
+	return u
+}
+
+// UserProfileUpdate contains profile fields to update, nil fields are kept unchanged
+type UserProfileUpdate struct {
+	Name     *string
+	ImageURL *string
+	Bio      *string
+}
+
+// FriendProfile is the public profile of a friend, hydrated with languages and country
+// from the matching information of the friend
+type FriendProfile struct {
+	PublicUser        `bson:",inline" json:",inline"`
+	Native            string   `bson:"native,omitempty"    json:"native,omitempty"`
+	Learnings         []string `bson:"learnings,omitempty" json:"learnings,omitempty"`
+	Country           string   `bson:"country,omitempty"   json:"country,omitempty"`
+	MutualFriendCount int      `bson:"mutualFriendCount"   json:"mutualFriendCount"`
+}
+
+// the partner bot is a user which practises with learners when no human partner is available,
+// it is identified by a firebaseUID that no firebase user could have
+const (
+	PartnerBotFirebaseUID = "blinders:partner-bot"
+	PartnerBotName        = "Blinders Partner"
+)
+
 // not use embedded conversation now
 // we could optimize conversation query by this later
 // also we can add more fields to embedded conversation like individual settings
@@ -34,19 +134,157 @@ const (
 	FriendStatusPending  FriendRequestStatus = "pending"
 	FriendStatusAccepted FriendRequestStatus = "accepted"
 	FriendStatusDenied   FriendRequestStatus = "denied"
+	// withdrawn by the sender
+	FriendStatusCancelled FriendRequestStatus = "cancelled"
 )
 
 type FriendRequest struct {
-	ID        primitive.ObjectID  `bson:"_id"       json:"id"`
-	From      primitive.ObjectID  `bson:"from"      json:"from"`
-	To        primitive.ObjectID  `bson:"to"        json:"to"`
-	Status    FriendRequestStatus `bson:"status"    json:"status"`
-	CreatedAt primitive.DateTime  `bson:"createdAt" json:"createdAt"`
-	UpdatedAt primitive.DateTime  `bson:"updatedAt" json:"updatedAt"`
+	ID        primitive.ObjectID  `bson:"_id"            json:"id"`
+	From      primitive.ObjectID  `bson:"from"           json:"from"`
+	To        primitive.ObjectID  `bson:"to"             json:"to"`
+	Status    FriendRequestStatus `bson:"status"         json:"status"`
+	CreatedAt primitive.DateTime  `bson:"createdAt"      json:"createdAt"`
+	UpdatedAt primitive.DateTime  `bson:"updatedAt"      json:"updatedAt"`
+	// Pair is the unordered pair of users, so that users can have only one pending request between them
+	Pair string `bson:"pair,omitempty" json:"-"`
+}
+
+type FeedbackStatus string
+
+const (
+	FeedbackNew      FeedbackStatus = "new"
+	FeedbackTriaged  FeedbackStatus = "triaged"
+	FeedbackResolved FeedbackStatus = "resolved"
+)
+
+// FeedbackStatusTransitions maps statuses to the statuses which feedback could move to,
+// resolved feedback could be reopened as triaged
+var FeedbackStatusTransitions = map[FeedbackStatus][]FeedbackStatus{
+	FeedbackNew:      {FeedbackTriaged, FeedbackResolved},
+	FeedbackTriaged:  {FeedbackResolved},
+	FeedbackResolved: {FeedbackTriaged},
 }
 
+type FeedbackCategory string
+
+const (
+	FeedbackBug     FeedbackCategory = "bug"
+	FeedbackFeature FeedbackCategory = "feature"
+	FeedbackContent FeedbackCategory = "content"
+	FeedbackOther   FeedbackCategory = "other"
+)
+
+var FeedbackCategories = []FeedbackCategory{FeedbackBug, FeedbackFeature, FeedbackContent, FeedbackOther}
+
+// Feedback is submitted by users and triaged by moderators. AppVersion, Device and Screenshots (keys of
+// the private storage) are optionally attached by the user, Notes and AssigneeID are internal to moderators.
+// Feedback submitted before the status workflow has no status, which is considered as new.
 type Feedback struct {
-	UserID    primitive.ObjectID `json:"userID,omitempty"    bson:"userID"`
-	Comment   string             `json:"comment,omitempty"   bson:"comment"`
-	CreatedAt primitive.DateTime `json:"createdAt,omitempty" bson:"createdAt"`
+	ID          primitive.ObjectID  `json:"id"                   bson:"_id"`
+	UserID      primitive.ObjectID  `json:"userID,omitempty"     bson:"userID"`
+	Comment     string              `json:"comment,omitempty"    bson:"comment"`
+	Category    FeedbackCategory    `json:"category,omitempty"   bson:"category,omitempty"`
+	AppVersion  string              `json:"appVersion,omitempty" bson:"appVersion,omitempty"`
+	Device      string              `json:"device,omitempty"     bson:"device,omitempty"`
+	Screenshots []string            `json:"screenshots"          bson:"screenshots,omitempty"`
+	Status      FeedbackStatus      `json:"status"               bson:"status,omitempty"`
+	AssigneeID  *primitive.ObjectID `json:"assigneeId,omitempty" bson:"assigneeId"`
+	Notes       []FeedbackNote      `json:"notes,omitempty"      bson:"notes,omitempty"`
+	CreatedAt   primitive.DateTime  `json:"createdAt,omitempty"  bson:"createdAt"`
+	UpdatedAt   primitive.DateTime  `json:"updatedAt,omitempty"  bson:"updatedAt,omitempty"`
+}
+
+// Public is the feedback without internal fields, which is returned to the user who submitted it
+func (f Feedback) Public() Feedback {
+	f.AssigneeID = nil
+	f.Notes = nil
+	return f
+}
+
+// normalize fills fields of feedback submitted before the status workflow
+func (f *Feedback) normalize() {
+	if f.Status == "" {
+		f.Status = FeedbackNew
+	}
+	if f.Category == "" {
+		f.Category = FeedbackOther
+	}
+	if f.Screenshots == nil {
+		f.Screenshots = []string{}
+	}
+}
+
+type FeedbackNote struct {
+	AuthorID  primitive.ObjectID `json:"authorId"  bson:"authorId"`
+	Content   string             `json:"content"   bson:"content"`
+	CreatedAt primitive.DateTime `json:"createdAt" bson:"createdAt"`
+}
+
+// FeedbackFilter filters feedback by the non-empty fields
+type FeedbackFilter struct {
+	Status     FeedbackStatus
+	Category   FeedbackCategory
+	AssigneeID *primitive.ObjectID
+	UserID     *primitive.ObjectID
+}
+
+// FeedbackTriage is the partial update of feedback by moderators, nil fields are not changed.
+// UnsetAssignee removes the assignee.
+type FeedbackTriage struct {
+	Status        *FeedbackStatus
+	Category      *FeedbackCategory
+	AssigneeID    *primitive.ObjectID
+	UnsetAssignee bool
+}
+
+type AccountJobType string
+
+const (
+	// AccountDeletionJob removes or anonymizes data of the user in every database
+	AccountDeletionJob AccountJobType = "deletion"
+	// AccountExportJob collects data of the user in every database into an archive
+	AccountExportJob AccountJobType = "export"
+)
+
+type AccountJobStatus string
+
+const (
+	AccountJobPending   AccountJobStatus = "pending"
+	AccountJobRunning   AccountJobStatus = "running"
+	AccountJobCompleted AccountJobStatus = "completed"
+	AccountJobFailed    AccountJobStatus = "failed"
+)
+
+// AccountJob is a long running job on data of a user, e.g. account deletion. Completed steps are recorded
+// in order, so a failed or interrupted job is resumed from the first step which is not recorded.
+// AuthID (firebaseUID) authorizes the user to get the job after the user is deleted,
+// Result is the storage key of the archive of export jobs, which is only downloaded by the user of the job
+// through the rest api.
+type AccountJob struct {
+	ID        primitive.ObjectID `bson:"_id"              json:"id"`
+	UserID    primitive.ObjectID `bson:"userId"           json:"userId"`
+	AuthID    string             `bson:"authId"           json:"-"`
+	Type      AccountJobType     `bson:"type"             json:"type"`
+	Status    AccountJobStatus   `bson:"status"           json:"status"`
+	Steps     []AccountJobStep   `bson:"steps"            json:"steps"`
+	Error     string             `bson:"error,omitempty"  json:"error,omitempty"`
+	Result    string             `bson:"result,omitempty" json:"-"`
+	CreatedAt primitive.DateTime `bson:"createdAt"        json:"createdAt"`
+	UpdatedAt primitive.DateTime `bson:"updatedAt"        json:"updatedAt"`
+}
+
+type AccountJobStep struct {
+	Name        string             `bson:"name"        json:"name"`
+	Affected    int64              `bson:"affected"    json:"affected"` // number of documents or keys processed by the step
+	CompletedAt primitive.DateTime `bson:"completedAt" json:"completedAt"`
+}
+
+// CompletedStep checks if the step is recorded as completed
+func (j AccountJob) CompletedStep(name string) bool {
+	for _, step := range j.Steps {
+		if step.Name == name {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,42 @@
+package usersdb_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"blinders/packages/db/usersdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserProjections(t *testing.T) {
+	user := usersdb.User{
+		ID:          primitive.NewObjectID(),
+		Name:        "user",
+		Email:       "user@example.com",
+		FirebaseUID: "firebase-uid",
+		FriendIDs:   []primitive.ObjectID{primitive.NewObjectID()},
+	}
+
+	public, _ := json.Marshal(user.Public())
+	assert.NotContains(t, string(public), "email")
+	assert.NotContains(t, string(public), "firebaseUID")
+	assert.NotContains(t, string(public), "friends")
+
+	self, _ := json.Marshal(user.Self())
+	assert.Contains(t, string(self), user.Email)
+	assert.NotContains(t, string(self), "firebaseUID")
+	assert.Equal(t, user.FriendIDs, user.Self().FriendIDs)
+	assert.NotNil(t, usersdb.User{}.Self().FriendIDs)
+	assert.NotContains(t, string(public), "blocked")
+}
+
+func TestUserWithSearchFields(t *testing.T) {
+	user := usersdb.User{Name: "Nguyễn Văn A", Email: "User@Example.com"}.WithSearchFields()
+	assert.Equal(t, "nguyễn văn a", user.SearchName)
+	assert.Equal(t, "user@example.com", user.SearchEmail)
+
+	data, _ := json.Marshal(user)
+	assert.NotContains(t, string(data), "searchName")
+}
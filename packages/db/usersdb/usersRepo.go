@@ -2,9 +2,16 @@ package usersdb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"blinders/packages/db/matchingdb"
+	dbutils "blinders/packages/db/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,6 +19,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrNotFriends = errors.New("users are not friends")
+
 type UsersRepo struct {
 	*mongo.Collection
 }
@@ -21,12 +30,18 @@ func NewUsersRepo(db *mongo.Database) *UsersRepo {
 	ctx, cal := context.WithTimeout(context.Background(), time.Second)
 	defer cal()
 
-	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.M{"firebaseUID": 1},
-		Options: options.Index().SetUnique(true),
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.M{"firebaseUID": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		// lowercase fields for case-insensitive prefix search, _id is in the keys so that the cursor and excluded
+		// users are filtered on the index. Matches are sorted by _id in memory, queries have minimum lengths.
+		{Keys: bson.D{{Key: "searchName", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "searchEmail", Value: 1}, {Key: "_id", Value: 1}}},
 	})
 	if err != nil {
-		log.Println("can not create index for firebaseUID:", err)
+		log.Println("can not create indexes for users:", err)
 		return nil
 	}
 
@@ -37,6 +52,7 @@ func (r *UsersRepo) InsertNewUser(u User) (User, error) {
 	ctx, cal := context.WithTimeout(context.Background(), time.Second)
 	defer cal()
 
+	u = u.WithSearchFields()
 	_, err := r.InsertOne(ctx, u)
 
 	return u, err
@@ -47,6 +63,7 @@ func (r *UsersRepo) InsertNewRawUser(u User) (User, error) {
 	ctx, cal := context.WithTimeout(context.Background(), time.Second)
 	defer cal()
 
+	u = u.WithSearchFields()
 	u.ID = primitive.NewObjectID()
 	now := primitive.NewDateTimeFromTime(time.Now())
 	u.CreatedAt = now
@@ -67,6 +84,34 @@ func (r *UsersRepo) GetUserByID(id primitive.ObjectID) (User, error) {
 	return user, err
 }
 
+// UpdateUserProfile updates profile fields of the user and returns the updated user
+func (r *UsersRepo) UpdateUserProfile(id primitive.ObjectID, update UserProfileUpdate) (User, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	set := bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())}
+	if update.Name != nil {
+		set["name"] = *update.Name
+		set["searchName"] = strings.ToLower(*update.Name)
+	}
+	if update.ImageURL != nil {
+		set["imageURL"] = *update.ImageURL
+	}
+	if update.Bio != nil {
+		set["bio"] = *update.Bio
+	}
+
+	var user User
+	err := r.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+
+	return user, err
+}
+
 func (r *UsersRepo) GetUserByFirebaseUID(uid string) (User, error) {
 	ctx, cal := context.WithTimeout(context.Background(), time.Second)
 	defer cal()
@@ -77,6 +122,36 @@ func (r *UsersRepo) GetUserByFirebaseUID(uid string) (User, error) {
 	return user, err
 }
 
+// GetOrInsertPartnerBot returns the practice partner bot user, the bot is created at the first call
+func (r *UsersRepo) GetOrInsertPartnerBot() (User, error) {
+	ctx, cal := context.WithTimeout(context.Background(), time.Second)
+	defer cal()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	returnDocument := options.After
+	upsert := true
+	var bot User
+	err := r.FindOneAndUpdate(ctx,
+		bson.M{"firebaseUID": PartnerBotFirebaseUID},
+		bson.M{"$setOnInsert": User{
+			ID:          primitive.NewObjectID(),
+			Name:        PartnerBotName,
+			FirebaseUID: PartnerBotFirebaseUID,
+			FriendIDs:   make([]primitive.ObjectID, 0),
+			IsBot:       true,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}.WithSearchFields()},
+		&options.FindOneAndUpdateOptions{ReturnDocument: &returnDocument, Upsert: &upsert},
+	).Decode(&bot)
+	if err != nil {
+		log.Println("can not get partner bot:", err)
+		return User{}, fmt.Errorf("something went wrong when getting partner bot")
+	}
+
+	return bot, nil
+}
+
 func (r *UsersRepo) GetUserByEmail(email string) (User, error) {
 	ctx, cal := context.WithTimeout(context.Background(), time.Second)
 	defer cal()
@@ -121,3 +196,355 @@ func (r *UsersRepo) AddFriend(user1ID primitive.ObjectID, user2ID primitive.Obje
 
 	return nil
 }
+
+// EnsureFriendsWithContext adds the friend link on both users like AddFriend, but it is idempotent,
+// an existing link on any of the users succeeds (e.g. retry of accepting a friend request).
+// The context could be a transaction context.
+func (r *UsersRepo) EnsureFriendsWithContext(
+	ctx context.Context,
+	user1ID primitive.ObjectID,
+	user2ID primitive.ObjectID,
+) error {
+	result, err := r.BulkWrite(
+		ctx,
+		[]mongo.WriteModel{
+			mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": user1ID}).
+				SetUpdate(bson.M{"$addToSet": bson.M{"friends": user2ID}}),
+			mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": user2ID}).
+				SetUpdate(bson.M{"$addToSet": bson.M{"friends": user1ID}}),
+		},
+	)
+	if err != nil {
+		log.Println("can not add friend:", err)
+		return fmt.Errorf("something went wrong")
+	} else if result.MatchedCount != 2 {
+		// $addToSet does not modify users which already have the link, so only the matched users are checked
+		log.Println("wrong matched count when ensure friends")
+		return fmt.Errorf("update friend failed, user not found")
+	}
+
+	return nil
+}
+
+// RemoveFriend removes the friend link on both users in a transaction,
+// it fails with ErrNotFriends if none of the users has the link
+func (r *UsersRepo) RemoveFriend(user1ID primitive.ObjectID, user2ID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err := dbutils.RunTransaction(ctx, r.Database().Client(), func(ctx context.Context) error {
+		var modified int64
+		for _, ids := range [][2]primitive.ObjectID{{user1ID, user2ID}, {user2ID, user1ID}} {
+			result, err := r.UpdateOne(ctx,
+				bson.M{"_id": ids[0]},
+				bson.M{"$pull": bson.M{"friends": ids[1]}},
+			)
+			if err != nil {
+				return err
+			}
+			modified += result.ModifiedCount
+		}
+		// a half removed link (e.g. failure without transaction) could be removed again
+		if modified == 0 {
+			return ErrNotFriends
+		}
+		return nil
+	})
+	if err == ErrNotFriends {
+		return err
+	} else if err != nil {
+		log.Println("can not remove friend:", err)
+		return fmt.Errorf("something went wrong")
+	}
+
+	return nil
+}
+
+func (r *UsersRepo) GetUsersByIDs(ids []primitive.ObjectID) ([]User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	users := make([]User, 0)
+	cur, err := r.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Println("can not get users:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+	if err := cur.All(ctx, &users); err != nil {
+		log.Println("can not decode users:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+
+	return users, nil
+}
+
+// GetFriendProfilesOfUser returns profiles of friends of the user sorted by their ID, paginated by the after cursor,
+// with counts of mutual friends. Friends are looked up from the user in one aggregation, it returns
+// mongo.ErrNoDocuments if the user does not exist. Matching information is joined in the pipeline if
+// joinMatchInfo is set, which requires the matching collection to be in the same database as users.
+// $lookup with both localField and pipeline requires MongoDB 5.0 or later.
+func (r *UsersRepo) GetFriendProfilesOfUser(
+	userID primitive.ObjectID,
+	after *primitive.ObjectID,
+	limit int64,
+	joinMatchInfo bool,
+) ([]FriendProfile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	friendsPipeline := []bson.M{}
+	if after != nil {
+		friendsPipeline = append(friendsPipeline, bson.M{"$match": bson.M{"_id": bson.M{"$gt": *after}}})
+	}
+	friendsPipeline = append(friendsPipeline,
+		bson.M{"$sort": bson.M{"_id": 1}},
+		bson.M{"$limit": limit},
+		bson.M{"$project": bson.M{
+			"name":     1,
+			"imageURL": 1,
+			"bio":      1,
+			"isBot":    1,
+			"mutualFriendCount": bson.M{"$size": bson.M{"$setIntersection": []any{
+				bson.M{"$ifNull": []any{"$friends", []primitive.ObjectID{}}},
+				"$$userFriends",
+			}}},
+		}},
+	)
+	if joinMatchInfo {
+		friendsPipeline = append(friendsPipeline,
+			bson.M{"$lookup": bson.M{
+				"from":         matchingdb.MatchingCollection,
+				"localField":   "_id",
+				"foreignField": "userId",
+				"as":           "matchInfo",
+			}},
+			bson.M{"$set": bson.M{
+				"native":    bson.M{"$first": "$matchInfo.native"},
+				"learnings": bson.M{"$first": "$matchInfo.learnings"},
+				"country":   bson.M{"$first": "$matchInfo.country"},
+			}},
+			bson.M{"$project": bson.M{"matchInfo": 0}},
+		)
+	}
+
+	// friends are matched by the _id index with localField, then paginated by the pipeline
+	cur, err := r.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"_id": userID}},
+		{"$lookup": bson.M{
+			"from":         UsersCollection,
+			"localField":   "friends",
+			"foreignField": "_id",
+			"let":          bson.M{"userFriends": bson.M{"$ifNull": []any{"$friends", []primitive.ObjectID{}}}},
+			"pipeline":     friendsPipeline,
+			"as":           "friendProfiles",
+		}},
+		{"$project": bson.M{"friendProfiles": 1}},
+	})
+	if err != nil {
+		log.Println("can not get friends:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		if err := cur.Err(); err != nil {
+			log.Println("can not get friends:", err)
+			return nil, fmt.Errorf("something went wrong")
+		}
+		return nil, mongo.ErrNoDocuments
+	}
+	var result struct {
+		FriendProfiles []FriendProfile `bson:"friendProfiles"`
+	}
+	if err := cur.Decode(&result); err != nil {
+		log.Println("can not decode friends:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+	if result.FriendProfiles == nil {
+		result.FriendProfiles = make([]FriendProfile, 0)
+	}
+
+	return result.FriendProfiles, nil
+}
+
+// RemoveUserFromFriendLists removes the user from friend and blocked lists of other users
+func (r *UsersRepo) RemoveUserFromFriendLists(userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.UpdateMany(ctx,
+		bson.M{"$or": []bson.M{{"friends": userID}, {"blocked": userID}}},
+		bson.M{"$pull": bson.M{"friends": userID, "blocked": userID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+const (
+	// MinNameSearchQueryLength is the minimum length of search queries, which bounds the matches of a prefix
+	// that are sorted in memory
+	MinNameSearchQueryLength = 2
+	// MinEmailSearchQueryLength is the minimum length of queries to search by email prefix,
+	// so that emails could not be enumerated by short prefixes
+	MinEmailSearchQueryLength = 5
+)
+
+// SearchUsers returns public projections of users whose name or email starts with the query case-insensitively,
+// sorted by ID and paginated by the after cursor. Queries must have at least MinNameSearchQueryLength characters,
+// emails are only searched by queries of at least MinEmailSearchQueryLength characters. The searcher, users blocked by the searcher and users blocking
+// the searcher are excluded.
+func (r *UsersRepo) SearchUsers(
+	searcher User,
+	query string,
+	after *primitive.ObjectID,
+	limit int64,
+) ([]PublicUser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if utf8.RuneCountInString(query) < MinNameSearchQueryLength {
+		return nil, fmt.Errorf("query must have at least %d characters", MinNameSearchQueryLength)
+	}
+
+	prefix := bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToLower(query))}
+	matches := []bson.M{{"searchName": prefix}}
+	if utf8.RuneCountInString(query) >= MinEmailSearchQueryLength {
+		matches = append(matches, bson.M{"searchEmail": prefix})
+	}
+	idFilter := bson.M{"$nin": append([]primitive.ObjectID{searcher.ID}, searcher.BlockedIDs...)}
+	if after != nil {
+		idFilter["$gt"] = *after
+	}
+
+	cur, err := r.Find(ctx,
+		bson.M{
+			"$or":     matches,
+			"_id":     idFilter,
+			"blocked": bson.M{"$ne": searcher.ID},
+		},
+		options.Find().
+			SetSort(bson.M{"_id": 1}).
+			SetLimit(limit).
+			SetProjection(bson.M{"name": 1, "imageURL": 1, "bio": 1, "isBot": 1}),
+	)
+	if err != nil {
+		log.Println("can not search users:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+
+	users := make([]PublicUser, 0)
+	if err := cur.All(ctx, &users); err != nil {
+		log.Println("can not decode users:", err)
+		return nil, fmt.Errorf("something went wrong")
+	}
+
+	return users, nil
+}
+
+// BackfillSearchFields sets the search fields of users which are created before the fields are introduced
+func (r *UsersRepo) BackfillSearchFields() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	result, err := r.UpdateMany(ctx,
+		bson.M{"searchName": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"searchName":  bson.M{"$toLower": "$name"},
+			"searchEmail": bson.M{"$toLower": "$email"},
+		}}}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// BlockUser adds the blocked user to the blocked list of the user, blocked users are hidden from each other in search
+func (r *UsersRepo) BlockUser(userID primitive.ObjectID, blockedID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$addToSet": bson.M{"blocked": blockedID},
+			"$set":      bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+		},
+	)
+	if err != nil {
+		log.Println("can not block user:", err)
+		return fmt.Errorf("something went wrong")
+	}
+
+	return nil
+}
+
+func (r *UsersRepo) UnblockUser(userID primitive.ObjectID, blockedID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$pull": bson.M{"blocked": blockedID},
+			"$set":  bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+		},
+	)
+	if err != nil {
+		log.Println("can not unblock user:", err)
+		return fmt.Errorf("something went wrong")
+	}
+
+	return nil
+}
+
+// AddRole grants the role to the user, roles are granted by admins (e.g. using the cli)
+func (r *UsersRepo) AddRole(userID primitive.ObjectID, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := r.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$addToSet": bson.M{"roles": role},
+			"$set":      bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+		},
+	)
+	if err != nil {
+		log.Println("can not add role:", err)
+		return fmt.Errorf("something went wrong")
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *UsersRepo) RemoveRole(userID primitive.ObjectID, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := r.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$pull": bson.M{"roles": role},
+			"$set":  bson.M{"updatedAt": primitive.NewDateTimeFromTime(time.Now())},
+		},
+	)
+	if err != nil {
+		log.Println("can not remove role:", err)
+		return fmt.Errorf("something went wrong")
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
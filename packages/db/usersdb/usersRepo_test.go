@@ -1,6 +1,8 @@
 package usersdb_test
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"blinders/packages/db/usersdb"
@@ -8,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -98,3 +101,104 @@ func TestAddFriend(t *testing.T) {
 	err = userRepo.AddFriend(user1.ID, user2.ID)
 	assert.NotNil(t, err)
 }
+
+func TestEnsureFriends(t *testing.T) {
+	user1, _ := userRepo.InsertNewRawUser(usersdb.User{
+		FirebaseUID: primitive.NewObjectID().Hex(),
+		FriendIDs:   make([]primitive.ObjectID, 0),
+	})
+	user2, _ := userRepo.InsertNewRawUser(usersdb.User{
+		FirebaseUID: primitive.NewObjectID().Hex(),
+		FriendIDs:   make([]primitive.ObjectID, 0),
+	})
+
+	err := userRepo.EnsureFriendsWithContext(context.Background(), user1.ID, user2.ID)
+	assert.Nil(t, err)
+	// ensuring an existing link succeeds
+	err = userRepo.EnsureFriendsWithContext(context.Background(), user2.ID, user1.ID)
+	assert.Nil(t, err)
+	err = userRepo.EnsureFriendsWithContext(context.Background(), user1.ID, primitive.NewObjectID())
+	assert.NotNil(t, err)
+}
+
+func TestRemoveFriend(t *testing.T) {
+	user1, _ := userRepo.InsertNewRawUser(usersdb.User{
+		FirebaseUID: primitive.NewObjectID().Hex(),
+		FriendIDs:   make([]primitive.ObjectID, 0),
+	})
+	user2, _ := userRepo.InsertNewRawUser(usersdb.User{
+		FirebaseUID: primitive.NewObjectID().Hex(),
+		FriendIDs:   make([]primitive.ObjectID, 0),
+	})
+	_ = userRepo.AddFriend(user1.ID, user2.ID)
+
+	err := userRepo.RemoveFriend(user2.ID, user1.ID)
+	assert.Nil(t, err)
+	removed, _ := userRepo.GetUserByID(user1.ID)
+	assert.Empty(t, removed.FriendIDs)
+
+	err = userRepo.RemoveFriend(user1.ID, user2.ID)
+	assert.Equal(t, usersdb.ErrNotFriends, err)
+}
+
+func TestGetFriendProfilesOfUser(t *testing.T) {
+	users := make([]usersdb.User, 3)
+	for i := range users {
+		users[i], _ = userRepo.InsertNewRawUser(usersdb.User{
+			FirebaseUID: primitive.NewObjectID().Hex(),
+			FriendIDs:   make([]primitive.ObjectID, 0),
+		})
+	}
+	_ = userRepo.AddFriend(users[0].ID, users[1].ID)
+	_ = userRepo.AddFriend(users[0].ID, users[2].ID)
+	_ = userRepo.AddFriend(users[1].ID, users[2].ID)
+
+	friends, err := userRepo.GetFriendProfilesOfUser(users[0].ID, nil, 1, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(friends))
+	assert.Equal(t, users[1].ID, friends[0].ID)
+	assert.Equal(t, 1, friends[0].MutualFriendCount)
+
+	friends, err = userRepo.GetFriendProfilesOfUser(users[0].ID, &friends[0].ID, 10, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(friends))
+	assert.Equal(t, users[2].ID, friends[0].ID)
+
+	_, err = userRepo.GetFriendProfilesOfUser(primitive.NewObjectID(), nil, 10, true)
+	assert.Equal(t, mongo.ErrNoDocuments, err)
+}
+
+func TestSearchUsers(t *testing.T) {
+	prefix := "Search" + primitive.NewObjectID().Hex()
+	searcher, _ := userRepo.InsertNewRawUser(usersdb.User{FirebaseUID: primitive.NewObjectID().Hex(), Name: prefix})
+	found, _ := userRepo.InsertNewRawUser(usersdb.User{FirebaseUID: primitive.NewObjectID().Hex(), Name: prefix + " Found"})
+	byEmail, _ := userRepo.InsertNewRawUser(usersdb.User{
+		FirebaseUID: primitive.NewObjectID().Hex(),
+		Email:       prefix + "@Example.com",
+	})
+	blocked, _ := userRepo.InsertNewRawUser(usersdb.User{FirebaseUID: primitive.NewObjectID().Hex(), Name: prefix + " Blocked"})
+	blocking, _ := userRepo.InsertNewRawUser(usersdb.User{FirebaseUID: primitive.NewObjectID().Hex(), Name: prefix + " Blocking"})
+	assert.Nil(t, userRepo.BlockUser(searcher.ID, blocked.ID))
+	assert.Nil(t, userRepo.BlockUser(blocking.ID, searcher.ID))
+	searcher, _ = userRepo.GetUserByID(searcher.ID)
+
+	users, err := userRepo.SearchUsers(searcher, strings.ToUpper(prefix), nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []usersdb.PublicUser{found.Public(), byEmail.Public()}, users)
+
+	users, err = userRepo.SearchUsers(searcher, prefix, &found.ID, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []usersdb.PublicUser{byEmail.Public()}, users)
+
+	users, err = userRepo.SearchUsers(searcher, prefix+".*", nil, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, users)
+
+	// emails are not searched by short queries
+	users, err = userRepo.SearchUsers(searcher, prefix[:usersdb.MinEmailSearchQueryLength-1], nil, 1000)
+	assert.Nil(t, err)
+	assert.NotContains(t, users, byEmail.Public())
+
+	_, err = userRepo.SearchUsers(searcher, prefix[:usersdb.MinNameSearchQueryLength-1], nil, 10)
+	assert.NotNil(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -47,6 +48,48 @@ func InitMongoDatabaseFromEnv(prefix ...string) (*mongo.Database, error) {
 	return client.Database(info.DBName), nil
 }
 
+// InitMongoDatabasesFromEnv inits the databases of the prefixes in order, databases with the same url
+// share one client so that they could be used in the same transaction
+func InitMongoDatabasesFromEnv(prefixes ...string) ([]*mongo.Database, error) {
+	infos := make([]MongoInfo, len(prefixes))
+	clients := make(map[string]*mongo.Client)
+	for i, prefix := range prefixes {
+		infos[i] = GetMongoInfoFromEnv(prefix)
+		clients[infos[i].URL] = nil
+	}
+
+	// connect to distinct urls concurrently to reduce cold-start
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	for url := range clients {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			client, err := InitMongoClient(url)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			clients[url] = client
+		}(url)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	dbs := make([]*mongo.Database, len(infos))
+	for i, info := range infos {
+		dbs[i] = clients[info.URL].Database(info.DBName)
+	}
+
+	return dbs, nil
+}
+
 // experimental: not work for now.
 //
 // return a Database channel, leverage goroutines to optimize aws lambda cold-start
@@ -0,0 +1,64 @@
+package dbutils
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// clients which are checked for transaction support, *mongo.Client -> bool
+var transactionSupports sync.Map
+
+// SupportsTransaction checks if the deployment of the client is a replica set or a sharded cluster,
+// standalone servers (e.g. local single node setups) do not support transactions.
+// The result is cached for the client.
+func SupportsTransaction(ctx context.Context, client *mongo.Client) bool {
+	if supported, ok := transactionSupports.Load(client); ok {
+		return supported.(bool)
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	admin := client.Database("admin")
+	err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		// servers older than 4.4.2 do not support hello command
+		err = admin.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	}
+	if err != nil {
+		log.Println("can not check transaction support:", err)
+		return false
+	}
+
+	supported := hello.SetName != "" || hello.Msg == "isdbgrid"
+	transactionSupports.Store(client, supported)
+
+	return supported
+}
+
+// RunTransaction runs fn in a transaction of the client, operations in fn must use the given context
+// and collections of the client to join the transaction. If the deployment does not support transactions,
+// fn runs without transaction and a failure could leave the earlier operations applied, fn is responsible
+// to compensate them or to make its operations idempotent, so that it is safe to retry.
+func RunTransaction(ctx context.Context, client *mongo.Client, fn func(ctx context.Context) error) error {
+	if !SupportsTransaction(ctx, client) {
+		return fn(ctx)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (any, error) {
+		return nil, fn(sessCtx)
+	})
+
+	return err
+}
@@ -35,6 +35,15 @@ type Explorer interface {
 	GetMatchingProfile(userID primitive.ObjectID) (*matchingdb.MatchInfo, error)
 	// UpdaterUserMatchInformation updates user match information to the database.
 	UpdaterUserMatchInformation(info *matchingdb.MatchInfo) (*matchingdb.MatchInfo, error)
+	// GetPublicProfiles attaches public projections of users to their matching information
+	GetPublicProfiles(infos ...matchingdb.MatchInfo) ([]Profile, error)
+}
+
+// Profile is the matching information of a user with the public projection of the user,
+// which is serialized to other users instead of the user document
+type Profile struct {
+	matchingdb.MatchInfo `json:",inline"`
+	User                 usersdb.PublicUser `json:"user"`
 }
 
 type MongoExplorer struct {
@@ -280,3 +289,30 @@ func (m *MongoExplorer) SuggestRandom(userID primitive.ObjectID) ([]matchingdb.M
 func (m *MongoExplorer) GetMatchingProfile(userID primitive.ObjectID) (*matchingdb.MatchInfo, error) {
 	return m.MatchingRepo.GetByUserID(userID)
 }
+
+func (m *MongoExplorer) GetPublicProfiles(infos ...matchingdb.MatchInfo) ([]Profile, error) {
+	ids := make([]primitive.ObjectID, len(infos))
+	for i, info := range infos {
+		ids[i] = info.UserID
+	}
+	users, err := m.UsersRepo.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	publicUsers := make(map[primitive.ObjectID]usersdb.PublicUser, len(users))
+	for _, u := range users {
+		publicUsers[u.ID] = u.Public()
+	}
+
+	profiles := make([]Profile, 0, len(infos))
+	for _, info := range infos {
+		user, ok := publicUsers[info.UserID]
+		if !ok {
+			// the user is deleted but its matching information is not cleaned up yet
+			continue
+		}
+		profiles = append(profiles, Profile{MatchInfo: info, User: user})
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"blinders/packages/db/usersdb"
+)
+
+// AuthError is the error contract of authentication shared by every adapter, it is responded
+// with the status and the JSON body {"error": Code, "message": Message}
+type AuthError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// Body is the JSON body of the error response
+func (e *AuthError) Body() []byte {
+	body, _ := json.Marshal(e)
+	return body
+}
+
+var (
+	ErrMissingToken = &AuthError{http.StatusUnauthorized, "missing_token", "missing bearer token"}
+	ErrInvalidToken = &AuthError{http.StatusUnauthorized, "invalid_token", "failed to verify jwt"}
+	ErrUserNotFound = &AuthError{http.StatusUnauthorized, "user_not_found", "failed to get user"}
+	// ErrUnauthenticated is returned by role and permission guards used without authentication
+	ErrUnauthenticated = &AuthError{http.StatusUnauthorized, "unauthenticated", "required user auth"}
+	ErrForbidden       = &AuthError{http.StatusForbidden, "forbidden", "insufficient permissions"}
+)
+
+// asAuthError converts errors of authentication to the error contract, unknown errors are invalid tokens
+func asAuthError(err error) *AuthError {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return authErr
+	}
+	return ErrInvalidToken
+}
+
+// UsersGetter gets the user of the auth ID, which is *usersdb.UsersRepo
+type UsersGetter interface {
+	GetUserByFirebaseUID(uid string) (usersdb.User, error)
+}
+
+// Authenticator verifies bearer tokens and loads users of requests, adapters of frameworks
+// only extract the token and respond errors of the Authenticator
+type Authenticator struct {
+	Manager Manager
+	Users   UsersGetter
+	// permit not checking if user exists, should only use to initialize user
+	CheckUser bool
+}
+
+func NewAuthenticator(m Manager, users UsersGetter, options ...MiddlewareOptions) *Authenticator {
+	return &Authenticator{
+		Manager:   m,
+		Users:     users,
+		CheckUser: len(options) == 0 || options[0].CheckUser,
+	}
+}
+
+// BearerToken extracts the token of the "Bearer <token>" authorization header
+func BearerToken(header string) (string, error) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrMissingToken
+	}
+	return token, nil
+}
+
+// AuthenticateHeader authenticates the authorization header of the request
+func (a Authenticator) AuthenticateHeader(ctx context.Context, header string) (*UserAuth, error) {
+	token, err := BearerToken(header)
+	if err != nil {
+		return nil, err
+	}
+	return a.Authenticate(ctx, token)
+}
+
+// Authenticate verifies the token and sets the user of the auth ID if users are checked,
+// errors are *AuthError
+func (a Authenticator) Authenticate(_ context.Context, token string) (*UserAuth, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	userAuth, err := verify(a.Manager, token)
+	if err != nil {
+		log.Println("failed to verify jwt:", err)
+		return nil, ErrInvalidToken
+	}
+
+	if a.CheckUser {
+		// currently, user.AuthID is firebaseUID
+		user, err := a.Users.GetUserByFirebaseUID(userAuth.AuthID)
+		if err != nil {
+			log.Println("failed to get user:", err)
+			return nil, ErrUserNotFound
+		}
+		userAuth.SetUser(user)
+		// users of caches have no roles, guards load the latest roles
+		if loader, ok := a.Users.(RolesLoader); ok {
+			userAuth.rolesLoader = func() ([]string, error) { return loader.LoadRoles(userAuth.AuthID, user.ID) }
+		}
+	}
+
+	return userAuth, nil
+}
+
+// authorize checks the user auth of the context for role and permission guards
+func authorize(userAuth *UserAuth, permitted func(userAuth *UserAuth) bool) error {
+	if userAuth == nil {
+		return ErrUnauthenticated
+	}
+	if err := userAuth.loadRoles(); err != nil {
+		log.Println("failed to load roles:", err)
+		return ErrUserNotFound
+	}
+	if !permitted(userAuth) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func hasRoles(roles []Role) func(*UserAuth) bool {
+	return func(userAuth *UserAuth) bool { return userAuth.HasRole(roles...) }
+}
+
+func hasPermissions(permissions []Permission) func(*UserAuth) bool {
+	return func(userAuth *UserAuth) bool { return userAuth.HasPermission(permissions...) }
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blinders/packages/db/usersdb"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeManager verifies tokens which are the auth IDs of users
+type fakeManager struct{}
+
+func (fakeManager) Verify(jwt string) (*UserAuth, error) {
+	if jwt == "invalid" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return &UserAuth{AuthID: jwt}, nil
+}
+
+type fakeUsers map[string]usersdb.User
+
+func (u fakeUsers) GetUserByFirebaseUID(uid string) (usersdb.User, error) {
+	user, ok := u[uid]
+	if !ok {
+		return usersdb.User{}, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func (u fakeUsers) GetUserByID(id primitive.ObjectID) (usersdb.User, error) {
+	for _, user := range u {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return usersdb.User{}, fmt.Errorf("user not found")
+}
+
+func TestAuthenticatorAdapters(t *testing.T) {
+	userID := primitive.NewObjectID()
+	authenticator := NewAuthenticator(fakeManager{}, fakeUsers{"uid": {ID: userID}})
+
+	app := fiber.New()
+	app.Get("/", authenticator.Fiber(), func(ctx *fiber.Ctx) error {
+		return ctx.SendString(ctx.Locals(UserAuthKey).(*UserAuth).ID)
+	})
+	lambdaHandler := authenticator.Lambda()(
+		func(ctx context.Context, _ events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK, Body: ctx.Value(UserAuthKey).(*UserAuth).ID}, nil
+		},
+	)
+	httpHandler := authenticator.HTTP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Context().Value(UserAuthKey).(*UserAuth).ID))
+	}))
+
+	// every adapter responds the same status and body
+	for _, c := range []struct {
+		header string
+		status int
+		body   string
+	}{
+		{"", http.StatusUnauthorized, string(ErrMissingToken.Body())},
+		{"Basic uid", http.StatusUnauthorized, string(ErrMissingToken.Body())},
+		{"Bearer invalid", http.StatusUnauthorized, string(ErrInvalidToken.Body())},
+		{"Bearer unknown", http.StatusUnauthorized, string(ErrUserNotFound.Body())},
+		{"Bearer uid", http.StatusOK, userID.Hex()},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", c.header)
+		res, err := app.Test(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(res.Body)
+		assert.Equal(t, c.status, res.StatusCode, c)
+		assert.Equal(t, c.body, string(body), c)
+
+		lambdaRes, err := lambdaHandler(context.Background(), events.APIGatewayV2HTTPRequest{
+			Headers: map[string]string{"authorization": c.header},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, c.status, lambdaRes.StatusCode, c)
+		assert.Equal(t, c.body, lambdaRes.Body, c)
+
+		recorder := httptest.NewRecorder()
+		httpHandler.ServeHTTP(recorder, req)
+		assert.Equal(t, c.status, recorder.Code, c)
+		assert.Equal(t, c.body, recorder.Body.String(), c)
+	}
+
+	var authErr AuthError
+	assert.Nil(t, json.Unmarshal(ErrForbidden.Body(), &authErr))
+	assert.Equal(t, "forbidden", authErr.Code)
+}
+
+func TestAuthorizeWebsocket(t *testing.T) {
+	userID := primitive.NewObjectID()
+	authenticator := NewAuthenticator(fakeManager{}, fakeUsers{"uid": {ID: userID}})
+
+	_, err := authenticator.AuthorizeWebsocket(context.Background(), "", "arn")
+	assert.Equal(t, ErrWebsocketUnauthorized, err)
+	_, err = authenticator.AuthorizeWebsocket(context.Background(), "unknown", "arn")
+	assert.Equal(t, ErrWebsocketUnauthorized, err)
+
+	res, err := authenticator.AuthorizeWebsocket(context.Background(), "uid", "arn")
+	assert.Nil(t, err)
+	assert.Equal(t, userID.Hex(), res.PrincipalID)
+	assert.Equal(t, []string{"arn"}, res.PolicyDocument.Statement[0].Resource)
+	assert.Contains(t, res.Context["user"], userID.Hex())
+}
+
+func TestHTTPRequirePermission(t *testing.T) {
+	handler := HTTPRequirePermission(PermissionManageRoles)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	userAuth := &UserAuth{}
+	userAuth.AddRoles(RoleModerator)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserAuthKey, userAuth))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
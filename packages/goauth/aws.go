@@ -2,9 +2,10 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
-	"net/http"
-	"strings"
+	"sync"
 
 	"blinders/packages/db/usersdb"
 
@@ -27,51 +28,44 @@ func LambdaLoggingMiddleware() LambdaMiddleware {
 
 func LambdaAuthMiddleware(
 	m Manager,
-	userRepo *usersdb.UsersRepo,
+	users UsersGetter,
 	options ...MiddlewareOptions,
 ) LambdaMiddleware {
+	return NewAuthenticator(m, users, options...).Lambda()
+}
+
+// LambdaAuthMiddlewareFromChan authenticates with the manager and the repo which are initialized concurrently,
+// they are received once on the first request
+func LambdaAuthMiddlewareFromChan(
+	mCh chan Manager,
+	userRepoCh chan *usersdb.UsersRepo,
+	options ...MiddlewareOptions,
+) LambdaMiddleware {
+	var (
+		once          sync.Once
+		authenticator *Authenticator
+	)
 	return func(next LambdaHandler) LambdaHandler {
 		return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-			auth, ok := event.Headers["authorization"]
-			if !ok {
-				return events.APIGatewayV2HTTPResponse{
-					StatusCode: http.StatusUnauthorized,
-					Body:       "missing authorization header",
-					Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-				}, nil
-			}
-			if !strings.HasPrefix(auth, "Bearer ") {
-				log.Println("invalid jwt, missing bearer token")
-				return events.APIGatewayV2HTTPResponse{
-					StatusCode: 400,
-					Body:       "missing bearer token",
-					Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-				}, nil
-			}
+			once.Do(func() {
+				authenticator = NewAuthenticator(<-mCh, nil, options...)
+				if authenticator.CheckUser {
+					authenticator.Users = <-userRepoCh
+				}
+			})
+			return authenticator.Lambda()(next)(ctx, event)
+		}
+	}
+}
 
-			jwt := strings.Split(auth, " ")[1]
-			userAuth, err := m.Verify(jwt)
+// Lambda authenticates API Gateway v2 requests and sets the user auth to UserAuthKey of the context
+func (a Authenticator) Lambda() LambdaMiddleware {
+	return func(next LambdaHandler) LambdaHandler {
+		return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			// headers of API Gateway v2 events are lowercase
+			userAuth, err := a.AuthenticateHeader(ctx, event.Headers["authorization"])
 			if err != nil {
-				log.Println("failed to verify jwt:", err)
-				return events.APIGatewayV2HTTPResponse{
-					StatusCode: http.StatusUnauthorized,
-					Body:       "failed to verify jwt",
-					Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-				}, nil
-			}
-
-			if len(options) == 0 || options[0].CheckUser {
-				// currently, user.AuthID is firebaseUID
-				user, err := userRepo.GetUserByFirebaseUID(userAuth.AuthID)
-				if err != nil {
-					log.Println("failed to get user:", err)
-					return events.APIGatewayV2HTTPResponse{
-						StatusCode: http.StatusUnauthorized,
-						Body:       "failed to get user",
-						Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-					}, nil
-				}
-				userAuth.ID = user.ID.Hex()
+				return lambdaAuthErrorResponse(err), nil
 			}
 
 			ctx = context.WithValue(ctx, UserAuthKey, userAuth)
@@ -80,59 +74,92 @@ func LambdaAuthMiddleware(
 	}
 }
 
-func LambdaAuthMiddlewareFromChan(
-	mCh chan Manager,
-	userRepoCh chan *usersdb.UsersRepo,
-	options ...MiddlewareOptions,
-) LambdaMiddleware {
-	return func(next LambdaHandler) LambdaHandler {
-		return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-			auth, ok := event.Headers["authorization"]
-			if !ok {
-				return events.APIGatewayV2HTTPResponse{
-					StatusCode: 400,
-					Body:       "missing authorization header",
-					Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-				}, nil
-			}
-			if !strings.HasPrefix(auth, "Bearer ") {
-				log.Println("invalid jwt, missing bearer token")
-				return events.APIGatewayV2HTTPResponse{
-					StatusCode: 400,
-					Body:       "missing bearer token",
-					Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-				}, nil
-			}
+// LambdaRequireRole permits users having any of the roles, it must be used after LambdaAuthMiddleware
+func LambdaRequireRole(roles ...Role) LambdaMiddleware {
+	return lambdaRequire(hasRoles(roles))
+}
 
-			jwt := strings.Split(auth, " ")[1]
-			m := <-mCh
-			userAuth, err := m.Verify(jwt)
-			if err != nil {
-				log.Println("failed to verify jwt:", err)
-				return events.APIGatewayV2HTTPResponse{
-					StatusCode: 400,
-					Body:       "failed to verify jwt",
-					Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-				}, nil
-			}
+// LambdaRequirePermission permits users having all of the permissions, it must be used after LambdaAuthMiddleware
+func LambdaRequirePermission(permissions ...Permission) LambdaMiddleware {
+	return lambdaRequire(hasPermissions(permissions))
+}
 
-			if len(options) == 0 || options[0].CheckUser {
-				// currently, user.AuthID is firebaseUID
-				userRepo := <-userRepoCh
-				user, err := userRepo.GetUserByFirebaseUID(userAuth.AuthID)
-				if err != nil {
-					log.Println("failed to get user:", err)
-					return events.APIGatewayV2HTTPResponse{
-						StatusCode: 400,
-						Body:       "failed to get user",
-						Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
-					}, nil
-				}
-				userAuth.ID = user.ID.Hex()
+func lambdaRequire(permitted func(userAuth *UserAuth) bool) LambdaMiddleware {
+	return func(next LambdaHandler) LambdaHandler {
+		return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			userAuth, _ := ctx.Value(UserAuthKey).(*UserAuth)
+			if err := authorize(userAuth, permitted); err != nil {
+				return lambdaAuthErrorResponse(err), nil
 			}
 
-			ctx = context.WithValue(ctx, UserAuthKey, userAuth)
 			return next(ctx, event)
 		}
 	}
 }
+
+func lambdaAuthErrorResponse(err error) events.APIGatewayV2HTTPResponse {
+	authErr := asAuthError(err)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: authErr.Status,
+		Body:       string(authErr.Body()),
+		Headers: map[string]string{
+			"Access-Control-Allow-Origin": "*",
+			"Content-Type":                "application/json",
+		},
+	}
+}
+
+// ErrWebsocketUnauthorized is returned by websocket authorizers to deny the connection,
+// API Gateway responds 401 for this exact message
+var ErrWebsocketUnauthorized = errors.New("Unauthorized") //nolint:revive
+
+// AuthorizeWebsocket authenticates the token of a websocket connection for API Gateway request authorizers.
+// The connection is allowed to invoke the method ARN, the user auth is passed to the integration
+// as the JSON "user" of the authorizer context.
+func (a Authenticator) AuthorizeWebsocket(
+	ctx context.Context,
+	token string,
+	methodArn string,
+) (events.APIGatewayCustomAuthorizerResponse, error) {
+	userAuth, err := a.Authenticate(ctx, token)
+	if err != nil {
+		log.Println("[authorizer] denied connection:", err)
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrWebsocketUnauthorized
+	}
+	return websocketPolicy(userAuth, methodArn), nil
+}
+
+// AuthorizeWebsocket redeems the ticket of a websocket connection for API Gateway request authorizers,
+// the ticket could not authorize other connections.
+func (s TicketStore) AuthorizeWebsocket(
+	ctx context.Context,
+	ticket string,
+	methodArn string,
+) (events.APIGatewayCustomAuthorizerResponse, error) {
+	userAuth, err := s.Redeem(ctx, ticket)
+	if err != nil {
+		log.Println("[authorizer] denied connection:", err)
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrWebsocketUnauthorized
+	}
+	return websocketPolicy(userAuth, methodArn), nil
+}
+
+func websocketPolicy(userAuth *UserAuth, methodArn string) events.APIGatewayCustomAuthorizerResponse {
+	userBytes, _ := json.Marshal(userAuth)
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID: userAuth.ID,
+		PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+			Version: "2012-10-17",
+			Statement: []events.IAMPolicyStatement{
+				{
+					Action:   []string{"execute-api:Invoke"},
+					Effect:   "Allow",
+					Resource: []string{methodArn},
+				},
+			},
+		},
+		Context: map[string]interface{}{
+			"user": string(userBytes),
+		},
+	}
+}
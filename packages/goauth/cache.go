@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"blinders/packages/db/usersdb"
+	"blinders/packages/utils"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultUserCacheCapacity  = 10000
+	DefaultMemoryUserCacheTTL = time.Minute
+	DefaultRedisUserCacheTTL  = time.Minute * 10
+	RedisUserCachePrefix      = "auth:user:"
+	userCacheTimeout          = time.Millisecond * 500
+)
+
+// CachedUser is the result of user lookups of authentication, only the user ID is cached since it does not
+// change while the user exists. Roles are loaded by role and permission guards, so that changes of roles
+// take effect on the next request.
+type CachedUser struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+// UserCache caches users by auth ID (firebaseUID). Account deletion evicts users of the redis cache, entries
+// of other caches are only evicted when their roles are loaded or after the TTL, which bounds how long tokens
+// of deleted users resolve to their user IDs.
+type UserCache interface {
+	Get(ctx context.Context, uid string) (*CachedUser, bool)
+	Set(ctx context.Context, uid string, user CachedUser)
+	Delete(ctx context.Context, uid string) error
+}
+
+// CacheableUsers gets users by auth ID and by ID, which is *usersdb.UsersRepo
+type CacheableUsers interface {
+	UsersGetter
+	GetUserByID(id primitive.ObjectID) (usersdb.User, error)
+}
+
+// RolesLoader is implemented by users getters which return users without roles (e.g. CachedUsers),
+// the Authenticator lets role and permission guards load the latest roles of the user
+type RolesLoader interface {
+	LoadRoles(uid string, userID primitive.ObjectID) ([]string, error)
+}
+
+// CachedUsers looks up users of the cache before the users getter, users found are cached.
+// Users of the cache only have ID, their roles are loaded by LoadRoles.
+type CachedUsers struct {
+	Users CacheableUsers
+	Cache UserCache
+}
+
+// NewCachedUsers puts the cache in front of the users getter, the getter is returned if the cache is nil
+func NewCachedUsers(users CacheableUsers, cache UserCache) UsersGetter {
+	if cache == nil {
+		return users
+	}
+	return &CachedUsers{Users: users, Cache: cache}
+}
+
+func (c CachedUsers) GetUserByFirebaseUID(uid string) (usersdb.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), userCacheTimeout)
+	defer cancel()
+
+	if cached, ok := c.Cache.Get(ctx, uid); ok {
+		return usersdb.User{ID: cached.ID, FirebaseUID: uid}, nil
+	}
+
+	user, err := c.Users.GetUserByFirebaseUID(uid)
+	if err != nil {
+		return user, err
+	}
+	c.Cache.Set(ctx, uid, CachedUser{ID: user.ID})
+
+	return user, nil
+}
+
+// LoadRoles gets the latest roles of the user, the entry of the user is evicted if the user is not found
+func (c CachedUsers) LoadRoles(uid string, userID primitive.ObjectID) ([]string, error) {
+	user, err := c.Users.GetUserByID(userID)
+	if err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), userCacheTimeout)
+		defer cancel()
+		if err := c.Cache.Delete(ctx, uid); err != nil {
+			log.Println("can not evict cached user:", err)
+		}
+		return nil, err
+	}
+	return user.Roles, nil
+}
+
+// NewUserCacheFromEnv creates the cache of the AUTH_USER_CACHE environment variable: redis, which uses the
+// redis of REDIS_* variables and is shared by services, memory or none. The default is redis if REDIS_HOST is
+// set, since deletion of accounts only evicts users of redis, otherwise none. Memory caches are not evicted
+// by deletion, deleted users are resolved until DefaultMemoryUserCacheTTL.
+func NewUserCacheFromEnv() UserCache {
+	switch strings.ToLower(os.Getenv("AUTH_USER_CACHE")) {
+	case "none":
+		return nil
+	case "redis":
+		return NewRedisUserCache(utils.NewRedisClientFromEnv(context.Background()))
+	case "memory":
+		return NewLRUUserCache(DefaultUserCacheCapacity, DefaultMemoryUserCacheTTL)
+	default:
+		if os.Getenv("REDIS_HOST") == "" {
+			return nil
+		}
+		return NewRedisUserCache(utils.NewRedisClientFromEnv(context.Background()))
+	}
+}
+
+// LRUUserCache is an in-memory cache which evicts the least recently used users over the capacity,
+// entries expire after TTL to bound staleness of users deleted by other processes
+type LRUUserCache struct {
+	Capacity int
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+type lruUserEntry struct {
+	uid       string
+	user      CachedUser
+	expiresAt time.Time
+}
+
+func NewLRUUserCache(capacity int, ttl time.Duration) *LRUUserCache {
+	return &LRUUserCache{
+		Capacity: capacity,
+		TTL:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *LRUUserCache) Get(_ context.Context, uid string) (*CachedUser, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[uid]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruUserEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, uid)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	user := entry.user
+	return &user, true
+}
+
+func (c *LRUUserCache) Set(_ context.Context, uid string, user CachedUser) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruUserEntry{uid: uid, user: user, expiresAt: time.Now().Add(c.TTL)}
+	if element, ok := c.entries[uid]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[uid] = c.order.PushFront(entry)
+	for c.order.Len() > c.Capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruUserEntry).uid)
+	}
+}
+
+func (c *LRUUserCache) Delete(_ context.Context, uid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[uid]; ok {
+		c.order.Remove(element)
+		delete(c.entries, uid)
+	}
+	return nil
+}
+
+// RedisUserCache shares cached users between services and lambdas, errors of redis are logged
+// and treated as cache misses so that users are looked up from the database
+type RedisUserCache struct {
+	Client *redis.Client
+	TTL    time.Duration
+}
+
+func NewRedisUserCache(client *redis.Client) *RedisUserCache {
+	return &RedisUserCache{Client: client, TTL: DefaultRedisUserCacheTTL}
+}
+
+func (c RedisUserCache) Get(ctx context.Context, uid string) (*CachedUser, bool) {
+	data, err := c.Client.Get(ctx, RedisUserCachePrefix+uid).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Println("can not get cached user:", err)
+		}
+		return nil, false
+	}
+
+	user := &CachedUser{}
+	if err := json.Unmarshal(data, user); err != nil {
+		log.Println("can not decode cached user:", err)
+		return nil, false
+	}
+	return user, true
+}
+
+func (c RedisUserCache) Set(ctx context.Context, uid string, user CachedUser) {
+	data, _ := json.Marshal(user)
+	if err := c.Client.Set(ctx, RedisUserCachePrefix+uid, data, c.TTL).Err(); err != nil {
+		log.Println("can not cache user:", err)
+	}
+}
+
+func (c RedisUserCache) Delete(ctx context.Context, uid string) error {
+	return c.Client.Del(ctx, RedisUserCachePrefix+uid).Err()
+}
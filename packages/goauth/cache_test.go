@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"blinders/packages/db/usersdb"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// countingUsers counts lookups of users, a lookup takes the latency like a database round trip
+type countingUsers struct {
+	users   fakeUsers
+	latency time.Duration
+	lookups int
+}
+
+func (u *countingUsers) GetUserByFirebaseUID(uid string) (usersdb.User, error) {
+	u.lookups++
+	time.Sleep(u.latency)
+	return u.users.GetUserByFirebaseUID(uid)
+}
+
+func (u *countingUsers) GetUserByID(id primitive.ObjectID) (usersdb.User, error) {
+	u.lookups++
+	time.Sleep(u.latency)
+	return u.users.GetUserByID(id)
+}
+
+func TestLRUUserCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUUserCache(2, time.Minute)
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+
+	cache.Set(ctx, "a", CachedUser{ID: ids[0]})
+	cache.Set(ctx, "b", CachedUser{ID: ids[1]})
+	// a is used recently, so b is evicted over the capacity
+	_, ok := cache.Get(ctx, "a")
+	assert.True(t, ok)
+	cache.Set(ctx, "c", CachedUser{ID: ids[2]})
+	_, ok = cache.Get(ctx, "b")
+	assert.False(t, ok)
+	user, ok := cache.Get(ctx, "c")
+	assert.True(t, ok)
+	assert.Equal(t, ids[2], user.ID)
+
+	assert.Nil(t, cache.Delete(ctx, "c"))
+	_, ok = cache.Get(ctx, "c")
+	assert.False(t, ok)
+
+	cache.TTL = 0
+	cache.Set(ctx, "d", CachedUser{ID: ids[0]})
+	_, ok = cache.Get(ctx, "d")
+	assert.False(t, ok)
+}
+
+func TestCachedUsers(t *testing.T) {
+	userID := primitive.NewObjectID()
+	users := &countingUsers{users: fakeUsers{"uid": {ID: userID, Name: "user", Roles: []string{"admin"}}}}
+	cache := NewLRUUserCache(DefaultUserCacheCapacity, time.Minute)
+	cached := NewCachedUsers(users, cache)
+	assert.Equal(t, users, NewCachedUsers(users, nil))
+
+	for i := 0; i < 3; i++ {
+		user, err := cached.GetUserByFirebaseUID("uid")
+		assert.Nil(t, err)
+		assert.Equal(t, userID, user.ID)
+	}
+	assert.Equal(t, 1, users.lookups)
+	// only the ID is cached
+	user, _ := cached.GetUserByFirebaseUID("uid")
+	assert.Empty(t, user.Roles)
+
+	// missing users are not cached
+	for i := 0; i < 2; i++ {
+		_, err := cached.GetUserByFirebaseUID("unknown")
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, 3, users.lookups)
+
+	roles, err := cached.(RolesLoader).LoadRoles("uid", userID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+
+	// deleted users are evicted when their roles are loaded
+	delete(users.users, "uid")
+	_, err = cached.(RolesLoader).LoadRoles("uid", userID)
+	assert.NotNil(t, err)
+	_, ok := cache.Get(context.Background(), "uid")
+	assert.False(t, ok)
+}
+
+func TestCachedUsersGuards(t *testing.T) {
+	userID := primitive.NewObjectID()
+	users := fakeUsers{"uid": {ID: userID, Roles: []string{string(RoleAdmin)}}}
+	authenticator := NewAuthenticator(fakeManager{}, NewCachedUsers(users, NewLRUUserCache(10, time.Minute)))
+	authorizeAdmin := func() error {
+		userAuth, err := authenticator.Authenticate(context.Background(), "uid")
+		if err != nil {
+			return err
+		}
+		return authorize(userAuth, hasRoles([]Role{RoleAdmin}))
+	}
+
+	assert.Nil(t, authorizeAdmin())
+	assert.Nil(t, authorizeAdmin(), "roles are loaded for users of the cache")
+
+	// revoked roles take effect on the next request
+	users["uid"] = usersdb.User{ID: userID}
+	assert.Equal(t, ErrForbidden, authorizeAdmin())
+
+	delete(users, "uid")
+	assert.Equal(t, ErrUserNotFound, authorizeAdmin())
+}
+
+func TestNewUserCacheFromEnv(t *testing.T) {
+	t.Setenv("AUTH_USER_CACHE", "")
+	t.Setenv("REDIS_HOST", "")
+	assert.Nil(t, NewUserCacheFromEnv(), "no cache by default without redis")
+
+	t.Setenv("AUTH_USER_CACHE", "memory")
+	assert.IsType(t, &LRUUserCache{}, NewUserCacheFromEnv())
+}
+
+func BenchmarkAuthenticate(b *testing.B) {
+	users := fakeUsers{}
+	for i := 0; i < 100; i++ {
+		users[fmt.Sprint(i)] = usersdb.User{ID: primitive.NewObjectID()}
+	}
+
+	for _, c := range []struct {
+		name  string
+		cache UserCache
+	}{
+		{"database", nil},
+		{"memory", NewLRUUserCache(DefaultUserCacheCapacity, time.Minute)},
+	} {
+		authenticator := NewAuthenticator(fakeManager{}, NewCachedUsers(
+			&countingUsers{users: users, latency: time.Millisecond},
+			c.cache,
+		))
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = authenticator.Authenticate(context.Background(), fmt.Sprint(i%100))
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	FirebaseProvider = "firebase"
+	LocalProvider    = "local"
+	OIDCProvider     = "oidc"
+)
+
+// NewManagerFromEnv creates the manager of the AUTH_PROVIDER environment variable, which is firebase by default.
+// The firebase manager loads the admin config file, the local manager signs tokens with LOCAL_JWT_SECRET (HS256)
+// or the RSA private key of LOCAL_JWT_PRIVATE_KEY_FILE (RS256). The OIDC manager is configured by
+// OIDC_ISSUER, OIDC_AUDIENCES (comma separated client IDs), OIDC_JWKS_URL (discovered from the issuer if empty)
+// and OIDC_UID_PREFIX.
+func NewManagerFromEnv(firebaseConfigFile string) (Manager, error) {
+	switch provider := strings.ToLower(os.Getenv("AUTH_PROVIDER")); provider {
+	case "", FirebaseProvider:
+		return NewFirebaseManagerFromFile(firebaseConfigFile)
+	case LocalProvider:
+		return NewLocalJWTManagerFromEnv()
+	case OIDCProvider:
+		return NewOIDCManagerFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown auth provider: %s", provider)
+	}
+}
+
+func NewLocalJWTManagerFromEnv() (*LocalJWTManager, error) {
+	if secret := os.Getenv("LOCAL_JWT_SECRET"); secret != "" {
+		return NewHS256Manager([]byte(secret))
+	}
+	if keyFile := os.Getenv("LOCAL_JWT_PRIVATE_KEY_FILE"); keyFile != "" {
+		return NewRS256ManagerFromFile(keyFile)
+	}
+	return nil, fmt.Errorf("LOCAL_JWT_SECRET or LOCAL_JWT_PRIVATE_KEY_FILE is required for local auth")
+}
+
+func NewOIDCManagerFromEnv() (*OIDCManager, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	var audiences []string
+	for _, aud := range strings.Split(os.Getenv("OIDC_AUDIENCES"), ",") {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			audiences = append(audiences, aud)
+		}
+	}
+
+	jwksURL := os.Getenv("OIDC_JWKS_URL")
+	if jwksURL == "" && issuer != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), oidcFetchTimeout)
+		defer cancel()
+
+		var err error
+		jwksURL, err = DiscoverJWKSURL(ctx, http.DefaultClient, issuer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := NewOIDCManager(issuer, audiences, NewHTTPJWKSSource(jwksURL))
+	if err != nil {
+		return nil, err
+	}
+	m.Mapping.UIDPrefix = os.Getenv("OIDC_UID_PREFIX")
+
+	return m, nil
+}
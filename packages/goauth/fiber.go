@@ -1,13 +1,6 @@
 package auth
 
-import (
-	"log"
-	"strings"
-
-	"blinders/packages/db/usersdb"
-
-	"github.com/gofiber/fiber/v2"
-)
+import "github.com/gofiber/fiber/v2"
 
 type key string
 
@@ -20,45 +13,48 @@ type MiddlewareOptions struct {
 
 func FiberAuthMiddleware(
 	m Manager,
-	userRepo *usersdb.UsersRepo,
+	users UsersGetter,
 	options ...MiddlewareOptions,
 ) fiber.Handler {
-	return func(ctx *fiber.Ctx) error {
-		auth := ctx.Get("Authorization")
-		if auth == "" {
-			auth = ctx.Get("authorization")
-		}
-		if !strings.HasPrefix(auth, "Bearer ") {
-			log.Println("invalid jwt, missing bearer token")
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"message": "invalid jwt, missing bearer token",
-			})
-		}
+	return NewAuthenticator(m, users, options...).Fiber()
+}
 
-		jwt := strings.Split(auth, " ")[1]
-		userAuth, err := m.Verify(jwt)
+// Fiber authenticates requests and sets the user auth to UserAuthKey of locals
+func (a Authenticator) Fiber() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		userAuth, err := a.AuthenticateHeader(ctx.UserContext(), ctx.Get(fiber.HeaderAuthorization))
 		if err != nil {
-			log.Println("failed to verify jwt:", err)
-			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"message": "failed to verify jwt",
-			})
+			return sendFiberAuthError(ctx, err)
 		}
 
-		if len(options) == 0 || options[0].CheckUser {
-			// currently, user.AuthID is firebaseUID
-			user, err := userRepo.GetUserByFirebaseUID(userAuth.AuthID)
-			if err != nil {
-				log.Println("failed to get user:", err)
-				return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-					"message": "failed to get user",
-				})
-			}
+		ctx.Locals(UserAuthKey, userAuth)
 
-			userAuth.ID = user.ID.Hex()
-		}
+		return ctx.Next()
+	}
+}
 
-		ctx.Locals(UserAuthKey, userAuth)
+// RequireRole permits users having any of the roles, it must be used after FiberAuthMiddleware
+func RequireRole(roles ...Role) fiber.Handler {
+	return fiberRequire(hasRoles(roles))
+}
+
+// RequirePermission permits users having all of the permissions, it must be used after FiberAuthMiddleware
+func RequirePermission(permissions ...Permission) fiber.Handler {
+	return fiberRequire(hasPermissions(permissions))
+}
+
+func fiberRequire(permitted func(userAuth *UserAuth) bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		userAuth, _ := ctx.Locals(UserAuthKey).(*UserAuth)
+		if err := authorize(userAuth, permitted); err != nil {
+			return sendFiberAuthError(ctx, err)
+		}
 
 		return ctx.Next()
 	}
 }
+
+func sendFiberAuthError(ctx *fiber.Ctx, err error) error {
+	authErr := asAuthError(err)
+	return ctx.Status(authErr.Status).JSON(authErr)
+}
@@ -23,17 +23,10 @@ func (m FirebaseManager) Verify(jwt string) (*UserAuth, error) {
 		return nil, err
 	}
 
-	firebaseUID := authToken.UID
-	email := authToken.Claims["email"].(string)
-	name := authToken.Claims["name"].(string)
+	userAuth := userAuthFromClaims(authToken.UID, authToken.Claims)
+	userAuth.Provider = authToken.Firebase.SignInProvider
 
-	userAuth := UserAuth{
-		Email:  email,
-		Name:   name,
-		AuthID: firebaseUID,
-	}
-
-	return &userAuth, nil
+	return userAuth, nil
 }
 
 func NewFirebaseManager(adminConfig []byte) (*FirebaseManager, error) {
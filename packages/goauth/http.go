@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+func HTTPAuthMiddleware(
+	m Manager,
+	users UsersGetter,
+	options ...MiddlewareOptions,
+) func(http.Handler) http.Handler {
+	return NewAuthenticator(m, users, options...).HTTP()
+}
+
+// HTTP authenticates net/http requests and sets the user auth to UserAuthKey of the request context
+func (a Authenticator) HTTP() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userAuth, err := a.AuthenticateHeader(r.Context(), r.Header.Get("Authorization"))
+			if err != nil {
+				writeHTTPAuthError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserAuthKey, userAuth)))
+		})
+	}
+}
+
+// HTTPRequireRole permits users having any of the roles, it must be used after HTTPAuthMiddleware
+func HTTPRequireRole(roles ...Role) func(http.Handler) http.Handler {
+	return httpRequire(hasRoles(roles))
+}
+
+// HTTPRequirePermission permits users having all of the permissions, it must be used after HTTPAuthMiddleware
+func HTTPRequirePermission(permissions ...Permission) func(http.Handler) http.Handler {
+	return httpRequire(hasPermissions(permissions))
+}
+
+func httpRequire(permitted func(userAuth *UserAuth) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userAuth, _ := r.Context().Value(UserAuthKey).(*UserAuth)
+			if err := authorize(userAuth, permitted); err != nil {
+				writeHTTPAuthError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeHTTPAuthError(w http.ResponseWriter, err error) {
+	authErr := asAuthError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(authErr.Status)
+	_, _ = w.Write(authErr.Body())
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"blinders/packages/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultLocalJWTIssuer = "blinders-local"
+	DefaultLocalJWTTTL    = time.Hour
+)
+
+// LocalJWTManager signs and verifies tokens with a local key, so services and tests could run
+// without a firebase project. Tokens have the same claims as firebase ID tokens: the uid is the subject,
+// "email", "name" and "roles" claims are mapped the same way as FirebaseManager.Verify,
+// the provider of the user auth is "local".
+type LocalJWTManager struct {
+	Method    jwt.SigningMethod
+	Issuer    string
+	TTL       time.Duration
+	signKey   any
+	verifyKey any
+}
+
+// LocalClaims are claims of a token minted by LocalJWTManager
+type LocalClaims struct {
+	Email string   `json:"email,omitempty"`
+	Name  string   `json:"name,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewHS256Manager signs and verifies tokens with the shared secret
+func NewHS256Manager(secret []byte) (*LocalJWTManager, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("secret must have at least 32 bytes")
+	}
+
+	return &LocalJWTManager{
+		Method:    jwt.SigningMethodHS256,
+		Issuer:    DefaultLocalJWTIssuer,
+		TTL:       DefaultLocalJWTTTL,
+		signKey:   secret,
+		verifyKey: secret,
+	}, nil
+}
+
+// NewRS256Manager signs tokens with the private key and verifies them with its public key
+func NewRS256Manager(privateKey *rsa.PrivateKey) *LocalJWTManager {
+	return &LocalJWTManager{
+		Method:    jwt.SigningMethodRS256,
+		Issuer:    DefaultLocalJWTIssuer,
+		TTL:       DefaultLocalJWTTTL,
+		signKey:   privateKey,
+		verifyKey: &privateKey.PublicKey,
+	}
+}
+
+// NewRS256ManagerFromFile loads the PEM encoded (PKCS1 or PKCS8) RSA private key of the file
+func NewRS256ManagerFromFile(filename string) (*LocalJWTManager, error) {
+	pem, err := utils.GetFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can not load private key file: %v", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse private key: %v", err)
+	}
+	return NewRS256Manager(key), nil
+}
+
+// Sign mints a token of the claims, issuer, issued time and expiration are set if they are empty
+func (m LocalJWTManager) Sign(claims LocalClaims) (string, error) {
+	if claims.Subject == "" {
+		return "", fmt.Errorf("subject (uid) is required")
+	}
+
+	now := time.Now()
+	if claims.Issuer == "" {
+		claims.Issuer = m.Issuer
+	}
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(m.TTL))
+	}
+
+	return jwt.NewWithClaims(m.Method, claims).SignedString(m.signKey)
+}
+
+func (m LocalJWTManager) Verify(token string) (*UserAuth, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims,
+		func(*jwt.Token) (any, error) { return m.verifyKey, nil },
+		jwt.WithValidMethods([]string{m.Method.Alg()}),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := claims.GetSubject()
+	if err != nil || uid == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	userAuth := userAuthFromClaims(uid, claims)
+	userAuth.Provider = LocalProvider
+	return userAuth, nil
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalJWTManager(t *testing.T) {
+	_, err := NewHS256Manager([]byte("short"))
+	assert.NotNil(t, err)
+
+	hs, err := NewHS256Manager([]byte(strings.Repeat("s", 32)))
+	assert.Nil(t, err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	rs := NewRS256Manager(key)
+
+	for _, m := range []*LocalJWTManager{hs, rs} {
+		token, err := m.Sign(LocalClaims{
+			Email:            "user@example.com",
+			Name:             "user",
+			Roles:            []string{"moderator"},
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "uid"},
+		})
+		assert.Nil(t, err)
+
+		userAuth, err := m.Verify(token)
+		assert.Nil(t, err)
+		assert.Equal(t, "uid", userAuth.AuthID)
+		assert.Equal(t, "user@example.com", userAuth.Email)
+		assert.Equal(t, "user", userAuth.Name)
+		assert.Equal(t, []Role{RoleModerator}, userAuth.Roles)
+	}
+
+	// tokens are only verified by the manager of the signing key
+	token, _ := hs.Sign(LocalClaims{Email: "e", Name: "n", RegisteredClaims: jwt.RegisteredClaims{Subject: "uid"}})
+	_, err = rs.Verify(token)
+	assert.NotNil(t, err)
+
+	expired, _ := hs.Sign(LocalClaims{Email: "e", Name: "n", RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "uid",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}})
+	_, err = hs.Verify(expired)
+	assert.NotNil(t, err)
+
+	// users signed in by phone have no email and name
+	phone, _ := hs.Sign(LocalClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "uid"}})
+	userAuth, err := hs.Verify(phone)
+	assert.Nil(t, err)
+	assert.Equal(t, "", userAuth.Email)
+	assert.Equal(t, LocalProvider, userAuth.Provider)
+
+	_, err = hs.Sign(LocalClaims{Email: "e", Name: "n"})
+	assert.NotNil(t, err)
+}
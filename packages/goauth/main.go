@@ -1,13 +1,75 @@
 package auth
 
+import (
+	"fmt"
+
+	"blinders/packages/db/usersdb"
+)
+
+// UserAuth is the authenticated user of a request. Roles are sourced from the "roles" custom claim of the token
+// and roles stored in the users collection, permissions are granted by the roles.
+// Email and Name are empty if the token does not have them, e.g. users signed in by phone or anonymously.
 type UserAuth struct {
-	Email  string
-	Name   string
-	AuthID string // [deprecated], this field currently is firebaseUID, move to userAuth.ID instead
-	ID     string // hex string of models.User
+	Email       string
+	Name        string
+	AuthID      string // [deprecated], this field currently is firebaseUID, move to userAuth.ID instead
+	ID          string // hex string of models.User
+	Provider    string // sign-in provider, e.g. password, phone, google.com, apple.com
+	Roles       []Role
+	Permissions []Permission
+
+	// rolesLoader loads roles of the users collection for guards, if the user was resolved without roles
+	rolesLoader func() ([]string, error)
 }
 
-// TODO: add interface for role-based auth
 type Manager interface {
 	Verify(jwt string) (*UserAuth, error)
 }
+
+// verify verifies the jwt by the manager, panics of the manager are recovered as errors
+// so that middlewares respond unauthorized instead of crashing
+func verify(m Manager, jwt string) (userAuth *UserAuth, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			userAuth, err = nil, fmt.Errorf("failed to verify jwt: %v", r)
+		}
+	}()
+
+	return m.Verify(jwt)
+}
+
+// userAuthFromClaims maps claims of ID tokens to the user auth, email and name claims are optional
+func userAuthFromClaims(uid string, claims map[string]any) *UserAuth {
+	userAuth := UserAuth{AuthID: uid}
+	userAuth.Email, _ = claims["email"].(string)
+	userAuth.Name, _ = claims["name"].(string)
+	userAuth.AddRoles(rolesFromClaim(claims["roles"])...)
+
+	return &userAuth
+}
+
+// SetUser sets the user of the authenticated request and adds roles of the user
+func (u *UserAuth) SetUser(user usersdb.User) {
+	u.ID = user.ID.Hex()
+	for _, role := range user.Roles {
+		u.AddRoles(Role(role))
+	}
+}
+
+// loadRoles adds the latest roles of the user if they are loaded lazily, it is called by guards
+// before checking roles and permissions
+func (u *UserAuth) loadRoles() error {
+	if u.rolesLoader == nil {
+		return nil
+	}
+
+	roles, err := u.rolesLoader()
+	if err != nil {
+		return err
+	}
+	u.rolesLoader = nil
+	for _, role := range roles {
+		u.AddRoles(Role(role))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAuthFromClaims(t *testing.T) {
+	userAuth := userAuthFromClaims("uid", map[string]any{
+		"email": "user@example.com",
+		"name":  "user",
+		"roles": []any{"admin"},
+	})
+	assert.Equal(t, "uid", userAuth.AuthID)
+	assert.Equal(t, "user@example.com", userAuth.Email)
+	assert.Equal(t, "user", userAuth.Name)
+	assert.Equal(t, []Role{RoleAdmin}, userAuth.Roles)
+
+	// phone and anonymous users have no email and name, claims of unexpected types are ignored
+	userAuth = userAuthFromClaims("uid", map[string]any{"phone_number": "+84123456789", "name": nil})
+	assert.Equal(t, "uid", userAuth.AuthID)
+	assert.Equal(t, "", userAuth.Email)
+	assert.Equal(t, "", userAuth.Name)
+}
+
+// panicManager is a manager which panics on verifying, e.g. on unexpected claims
+type panicManager struct{}
+
+func (panicManager) Verify(string) (*UserAuth, error) {
+	panic("unexpected claims")
+}
+
+func TestAuthMiddlewaresRespondUnauthorizedOnPanics(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", FiberAuthMiddleware(panicManager{}, nil), func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	res, err := app.Test(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	handler := LambdaAuthMiddleware(panicManager{}, nil)(
+		func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK}, nil
+		},
+	)
+	lambdaRes, err := handler(context.Background(), events.APIGatewayV2HTTPRequest{
+		Headers: map[string]string{"authorization": "Bearer token"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, lambdaRes.StatusCode)
+}
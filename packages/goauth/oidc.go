@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultJWKSCacheTTL        = time.Hour
+	DefaultJWKSRefreshInterval = time.Minute
+	DefaultOIDCLeeway          = time.Second * 30
+	oidcFetchTimeout           = time.Second * 5
+)
+
+// JWK is a public JSON web key, only RSA and EC keys are supported
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey parses the key as *rsa.PublicKey or *ecdsa.PublicKey
+func (k JWK) PublicKey() (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %v", k.Kid, err)
+		}
+		e, err := decode(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid exponent of key %s", k.Kid)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(),
+			"P-384": elliptic.P384(),
+			"P-521": elliptic.P521(),
+		}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve of key %s: %s", k.Kid, k.Crv)
+		}
+		x, errX := decode(k.X)
+		y, errY := decode(k.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid coordinates of key %s", k.Kid)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("invalid coordinates of key %s", k.Kid)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type of key %s: %s", k.Kid, k.Kty)
+	}
+}
+
+// JWKSSource provides the current key set of an issuer, it is injected to verify tokens against local keys in tests
+type JWKSSource interface {
+	FetchJWKS(ctx context.Context) (*JWKS, error)
+}
+
+// StaticJWKSSource always provides the same key set
+type StaticJWKSSource JWKS
+
+func (s StaticJWKSSource) FetchJWKS(context.Context) (*JWKS, error) {
+	jwks := JWKS(s)
+	return &jwks, nil
+}
+
+// HTTPJWKSSource fetches the key set from the JWKS endpoint of the issuer
+type HTTPJWKSSource struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPJWKSSource(url string) *HTTPJWKSSource {
+	return &HTTPJWKSSource{URL: url, Client: http.DefaultClient}
+}
+
+func (s HTTPJWKSSource) FetchJWKS(ctx context.Context) (*JWKS, error) {
+	jwks := &JWKS{}
+	if err := getJSON(ctx, s.Client, s.URL, jwks); err != nil {
+		return nil, fmt.Errorf("can not fetch jwks: %v", err)
+	}
+	return jwks, nil
+}
+
+// DiscoverJWKSURL gets the JWKS endpoint from the OpenID configuration of the issuer
+func DiscoverJWKSURL(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	config := struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, client, url, &config); err != nil {
+		return "", fmt.Errorf("can not discover openid configuration: %v", err)
+	}
+	if config.Issuer != issuer || config.JWKSURI == "" {
+		return "", fmt.Errorf("invalid openid configuration of issuer %s", issuer)
+	}
+	return config.JWKSURI, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// CachedKeySet caches keys of the source by key ID. Keys are refetched after TTL, or when a token is signed
+// by an unknown key as the issuer rotates its keys, at most once per RefreshInterval.
+type CachedKeySet struct {
+	Source          JWKSSource
+	TTL             time.Duration
+	RefreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+func NewCachedKeySet(source JWKSSource) *CachedKeySet {
+	return &CachedKeySet{
+		Source:          source,
+		TTL:             DefaultJWKSCacheTTL,
+		RefreshInterval: DefaultJWKSRefreshInterval,
+	}
+}
+
+// Key returns the public key of the key ID
+func (s *CachedKeySet) Key(ctx context.Context, kid string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	key, ok := s.keys[kid]
+	expired := now.Sub(s.fetchedAt) >= s.TTL
+	if ok && !expired {
+		return key, nil
+	}
+	if expired || now.Sub(s.fetchedAt) >= s.RefreshInterval {
+		if err := s.refresh(ctx, now); err != nil {
+			// keep verifying with the cached keys if the source is unavailable
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		key, ok = s.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	return key, nil
+}
+
+func (s *CachedKeySet) refresh(ctx context.Context, now time.Time) error {
+	jwks, err := s.Source.FetchJWKS(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]any, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// unsupported keys of the set are skipped
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	s.keys, s.fetchedAt = keys, now
+
+	return nil
+}
+
+// ClaimMapping maps claims of ID tokens to the user auth. The UID claim is required, the others are optional
+// since providers do not always include them (e.g. Apple only sends the name on the first sign in).
+// UIDPrefix separates users of the provider from users of the other providers,
+// Provider is set as the sign-in provider of the user auth.
+// Roles is an explicit opt-in for providers which are trusted to grant roles, roles only come from
+// the users collection by default since any user of a third-party provider could have the claim.
+// Tokens whose EmailVerified claim is false are rejected, the email is not trusted otherwise.
+type ClaimMapping struct {
+	UID           string
+	Email         string
+	EmailVerified string
+	Name          string
+	Roles         string
+	UIDPrefix     string
+	Provider      string
+}
+
+var DefaultClaimMapping = ClaimMapping{UID: "sub", Email: "email", EmailVerified: "email_verified", Name: "name"}
+
+func (m ClaimMapping) UserAuth(claims jwt.MapClaims) (*UserAuth, error) {
+	uid, _ := claims[m.UID].(string)
+	if uid == "" {
+		return nil, fmt.Errorf("token has no %s claim", m.UID)
+	}
+
+	if m.EmailVerified != "" && isFalseClaim(claims[m.EmailVerified]) {
+		return nil, fmt.Errorf("email of token is not verified")
+	}
+
+	userAuth := UserAuth{AuthID: m.UIDPrefix + uid, Provider: m.Provider}
+	if m.Email != "" {
+		userAuth.Email, _ = claims[m.Email].(string)
+	}
+	if m.Name != "" {
+		userAuth.Name, _ = claims[m.Name].(string)
+	}
+	if m.Roles != "" {
+		userAuth.AddRoles(rolesFromClaim(claims[m.Roles])...)
+	}
+
+	return &userAuth, nil
+}
+
+// isFalseClaim checks boolean claims which are strings for some providers, e.g. "false" of Apple
+func isFalseClaim(claim any) bool {
+	switch v := claim.(type) {
+	case bool:
+		return !v
+	case string:
+		return v == "false"
+	default:
+		return false
+	}
+}
+
+// OIDCManager verifies ID tokens of an OpenID Connect issuer (e.g. Sign in with Apple or Google) against
+// the keys of the issuer, the token must be issued for one of the audiences (client IDs)
+type OIDCManager struct {
+	Issuer     string
+	Audiences  []string
+	Keys       *CachedKeySet
+	Mapping    ClaimMapping
+	Algorithms []string
+	Leeway     time.Duration
+}
+
+func NewOIDCManager(issuer string, audiences []string, source JWKSSource) (*OIDCManager, error) {
+	if issuer == "" || len(audiences) == 0 {
+		return nil, fmt.Errorf("issuer and audiences are required")
+	}
+
+	mapping := DefaultClaimMapping
+	mapping.Provider = issuer
+
+	return &OIDCManager{
+		Issuer:     issuer,
+		Audiences:  audiences,
+		Keys:       NewCachedKeySet(source),
+		Mapping:    mapping,
+		Algorithms: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
+		Leeway:     DefaultOIDCLeeway,
+	}, nil
+}
+
+func (m OIDCManager) Verify(token string) (*UserAuth, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcFetchTimeout)
+	defer cancel()
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			return m.Keys.Key(ctx, kid)
+		},
+		jwt.WithValidMethods(m.Algorithms),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(m.Leeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	audiences, err := claims.GetAudience()
+	if err != nil || !slices.ContainsFunc(audiences, func(aud string) bool { return slices.Contains(m.Audiences, aud) }) {
+		return nil, fmt.Errorf("token is not issued for the audiences")
+	}
+
+	return m.Mapping.UserAuth(claims)
+}
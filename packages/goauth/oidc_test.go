@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+// countingJWKSSource provides the mutable key set and counts fetches
+type countingJWKSSource struct {
+	jwks    JWKS
+	fetches int
+}
+
+func (s *countingJWKSSource) FetchJWKS(context.Context) (*JWKS, error) {
+	s.fetches++
+	jwks := s.jwks
+	return &jwks, nil
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) JWK {
+	return JWK{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+	}
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return signed
+}
+
+func testClaims(aud string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   aud,
+		"sub":   "uid",
+		"email": "user@example.com",
+		"roles": []string{"moderator"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestOIDCManager(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	source := &countingJWKSSource{jwks: JWKS{Keys: []JWK{rsaJWK("rsa", &rsaKey.PublicKey)}}}
+
+	_, err := NewOIDCManager(testIssuer, nil, source)
+	assert.NotNil(t, err)
+	m, err := NewOIDCManager(testIssuer, []string{"client"}, source)
+	assert.Nil(t, err)
+	m.Mapping.UIDPrefix = "apple:"
+
+	userAuth, err := m.Verify(signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, testClaims("client")))
+	assert.Nil(t, err)
+	assert.Equal(t, "apple:uid", userAuth.AuthID)
+	assert.Equal(t, "user@example.com", userAuth.Email)
+	assert.Equal(t, "", userAuth.Name)
+	// roles of third-party providers are not trusted unless the mapping opts in
+	assert.Empty(t, userAuth.Roles)
+	m.Mapping.Roles = "roles"
+	userAuth, err = m.Verify(signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, testClaims("client")))
+	assert.Nil(t, err)
+	assert.Equal(t, []Role{RoleModerator}, userAuth.Roles)
+
+	for _, verified := range []any{false, "false"} {
+		claims := testClaims("client")
+		claims["email_verified"] = verified
+		_, err = m.Verify(signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+		assert.NotNil(t, err, verified)
+	}
+	claims := testClaims("client")
+	claims["email_verified"] = "true"
+	_, err = m.Verify(signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+	assert.Nil(t, err)
+
+	_, err = m.Verify(signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, testClaims("other")))
+	assert.NotNil(t, err)
+	claims = testClaims("client")
+	claims["iss"] = "https://other.example.com"
+	_, err = m.Verify(signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+	assert.NotNil(t, err)
+	claims = testClaims("client")
+	delete(claims, "sub")
+	_, err = m.Verify(signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+	assert.NotNil(t, err)
+
+	// keys are cached, a token of an unknown key is not refetched within the refresh interval
+	ecToken := signTestToken(t, jwt.SigningMethodES256, "ec", ecKey, testClaims("client"))
+	source.jwks.Keys = append(source.jwks.Keys, ecJWK("ec", &ecKey.PublicKey))
+	_, err = m.Verify(ecToken)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, source.fetches)
+
+	// the rotated key is fetched after the refresh interval
+	m.Keys.RefreshInterval = 0
+	_, err = m.Verify(ecToken)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, source.fetches)
+}
+
+func TestHTTPJWKSSource(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(JWKS{Keys: []JWK{rsaJWK("rsa", &key.PublicKey)}})
+	})
+
+	url, err := DiscoverJWKSURL(context.Background(), server.Client(), server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/keys", url)
+
+	jwks, err := HTTPJWKSSource{URL: url, Client: server.Client()}.FetchJWKS(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, jwks.Keys, 1)
+	publicKey, err := jwks.Keys[0].PublicKey()
+	assert.Nil(t, err)
+	assert.True(t, key.PublicKey.Equal(publicKey))
+}
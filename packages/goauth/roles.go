@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Role string
+
+const (
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermissionReadFeedback   Permission = "feedback:read"
+	PermissionManageFeedback Permission = "feedback:manage"
+	PermissionModerateUsers  Permission = "users:moderate"
+	PermissionManageRoles    Permission = "roles:manage"
+)
+
+// RolePermissions maps roles to their permissions, users without roles only access their own resources
+var RolePermissions = map[Role][]Permission{
+	RoleModerator: {
+		PermissionReadFeedback,
+		PermissionManageFeedback,
+		PermissionModerateUsers,
+	},
+	RoleAdmin: {
+		PermissionReadFeedback,
+		PermissionManageFeedback,
+		PermissionModerateUsers,
+		PermissionManageRoles,
+	},
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := RolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role: %s", s)
+	}
+	return role, nil
+}
+
+// PermissionsOf returns the distinct permissions of the roles, unknown roles have no permission
+func PermissionsOf(roles ...Role) []Permission {
+	permissions := make([]Permission, 0)
+	for _, role := range roles {
+		for _, permission := range RolePermissions[role] {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// AddRoles adds the roles which the user does not have yet and updates permissions of the user
+func (u *UserAuth) AddRoles(roles ...Role) {
+	for _, role := range roles {
+		if role != "" && !slices.Contains(u.Roles, role) {
+			u.Roles = append(u.Roles, role)
+		}
+	}
+	u.Permissions = PermissionsOf(u.Roles...)
+}
+
+// HasRole checks if the user has any of the roles
+func (u UserAuth) HasRole(roles ...Role) bool {
+	return slices.ContainsFunc(roles, func(role Role) bool { return slices.Contains(u.Roles, role) })
+}
+
+// HasPermission checks if the user has all of the permissions
+func (u UserAuth) HasPermission(permissions ...Permission) bool {
+	for _, permission := range permissions {
+		if !slices.Contains(u.Permissions, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// rolesFromClaim parses roles of the "roles" custom claim, which is an array of role names
+func rolesFromClaim(claim any) []Role {
+	values, ok := claim.([]any)
+	if !ok {
+		return nil
+	}
+
+	roles := make([]Role, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			roles = append(roles, Role(s))
+		}
+	}
+	return roles
+}
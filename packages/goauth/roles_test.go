@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blinders/packages/db/usersdb"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAuthRoles(t *testing.T) {
+	userAuth := UserAuth{}
+	userAuth.AddRoles(rolesFromClaim([]any{"moderator", 1})...)
+	assert.Equal(t, []Role{RoleModerator}, userAuth.Roles)
+	assert.True(t, userAuth.HasRole(RoleModerator, RoleAdmin))
+	assert.True(t, userAuth.HasPermission(PermissionReadFeedback, PermissionManageFeedback))
+	assert.False(t, userAuth.HasPermission(PermissionReadFeedback, PermissionManageRoles))
+
+	userAuth.SetUser(usersdb.User{Roles: []string{"admin", "moderator"}})
+	assert.Equal(t, []Role{RoleModerator, RoleAdmin}, userAuth.Roles)
+	assert.True(t, userAuth.HasPermission(PermissionManageRoles))
+
+	_, err := ParseRole("root")
+	assert.NotNil(t, err)
+	assert.Nil(t, rolesFromClaim(nil))
+}
+
+func TestRequireRole(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		userAuth := &UserAuth{}
+		userAuth.AddRoles(Role(ctx.Get("X-Role")))
+		ctx.Locals(UserAuthKey, userAuth)
+		return ctx.Next()
+	})
+	app.Get("/admin", RequireRole(RoleAdmin), func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+	app.Get("/feedback", RequirePermission(PermissionReadFeedback), func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	for _, c := range []struct {
+		path   string
+		role   string
+		status int
+	}{
+		{"/admin", "admin", http.StatusOK},
+		{"/admin", "moderator", http.StatusForbidden},
+		{"/feedback", "moderator", http.StatusOK},
+		{"/feedback", "", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Header.Set("X-Role", c.role)
+		res, err := app.Test(req)
+		assert.Nil(t, err)
+		assert.Equal(t, c.status, res.StatusCode, c)
+	}
+}
+
+func TestLambdaRequirePermission(t *testing.T) {
+	handler := LambdaRequirePermission(PermissionManageRoles)(
+		func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK}, nil
+		},
+	)
+
+	res, _ := handler(context.Background(), events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	userAuth := &UserAuth{}
+	userAuth.AddRoles(RoleModerator)
+	res, _ = handler(context.WithValue(context.Background(), UserAuthKey, userAuth), events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	userAuth.AddRoles(RoleAdmin)
+	res, _ = handler(context.WithValue(context.Background(), UserAuthKey, userAuth), events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"blinders/packages/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultTicketTTL     = time.Second * 30
+	RedisTicketPrefix    = "auth:ticket:"
+	ticketSize           = 32
+	TicketQueryParam     = "ticket"
+	TicketHeader         = "Sec-WebSocket-Protocol"
+	TicketProtocolPrefix = "ticket."
+)
+
+var ErrInvalidTicket = &AuthError{http.StatusUnauthorized, "invalid_ticket", "ticket is invalid or used"}
+
+// TicketStore exchanges authenticated users for single-use tickets, so that websocket connections
+// are authorized without the bearer token in URLs which end up in access logs
+type TicketStore struct {
+	Client *redis.Client
+	TTL    time.Duration
+}
+
+func NewTicketStore(client *redis.Client) *TicketStore {
+	return &TicketStore{Client: client, TTL: DefaultTicketTTL}
+}
+
+// NewTicketStoreFromEnv returns nil if REDIS_HOST is not set. The redis client is not pinged, it connects
+// on the first command, so that an outage of redis only fails tickets instead of the service.
+func NewTicketStoreFromEnv() *TicketStore {
+	if os.Getenv("REDIS_HOST") == "" {
+		log.Println("REDIS_HOST is not set, websocket tickets are disabled")
+		return nil
+	}
+	return NewTicketStore(redis.NewClient(utils.RedisOptionsFromEnv()))
+}
+
+// Issue stores the user auth under a random ticket which expires after the TTL
+func (s TicketStore) Issue(ctx context.Context, userAuth UserAuth) (string, error) {
+	b := make([]byte, ticketSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+
+	data, _ := json.Marshal(userAuth)
+	if err := s.Client.Set(ctx, RedisTicketPrefix+ticket, data, s.TTL).Err(); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// Redeem gets the user auth of the ticket and deletes the ticket in one command, so that the ticket
+// could only be used once
+func (s TicketStore) Redeem(ctx context.Context, ticket string) (*UserAuth, error) {
+	if ticket == "" {
+		return nil, ErrMissingToken
+	}
+
+	data, err := s.Client.GetDel(ctx, RedisTicketPrefix+ticket).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidTicket
+	} else if err != nil {
+		log.Println("can not redeem ticket:", err)
+		return nil, ErrInvalidTicket
+	}
+
+	userAuth := &UserAuth{}
+	if err := json.Unmarshal(data, userAuth); err != nil {
+		log.Println("can not decode ticket:", err)
+		return nil, ErrInvalidTicket
+	}
+	return userAuth, nil
+}
+
+// TicketFromProtocols gets the ticket of the Sec-WebSocket-Protocol header, browsers could not set
+// headers of websocket connections but subprotocols, the ticket is sent as the "ticket.<ticket>" subprotocol.
+// The protocol is returned to be echoed by the server, which is required by browsers to open the connection.
+func TicketFromProtocols(header string) (ticket string, protocol string) {
+	for _, p := range strings.Split(header, ",") {
+		p = strings.TrimSpace(p)
+		if t, ok := strings.CutPrefix(p, TicketProtocolPrefix); ok && t != "" {
+			return t, p
+		}
+	}
+	return "", ""
+}
+
+// WebsocketTicket gets the ticket of the connection request of the query string or the Sec-WebSocket-Protocol
+// header, header names of API Gateway events are not canonicalized
+func WebsocketTicket(query map[string]string, headers map[string]string) string {
+	if ticket := query[TicketQueryParam]; ticket != "" {
+		return ticket
+	}
+	for name, value := range headers {
+		if strings.EqualFold(name, TicketHeader) {
+			ticket, _ := TicketFromProtocols(value)
+			return ticket
+		}
+	}
+	return ""
+}
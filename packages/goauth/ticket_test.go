@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTicketFromProtocols(t *testing.T) {
+	ticket, protocol := TicketFromProtocols("chat, ticket.abc-123_x")
+	assert.Equal(t, "abc-123_x", ticket)
+	assert.Equal(t, "ticket.abc-123_x", protocol)
+
+	ticket, protocol = TicketFromProtocols("chat, ticket.")
+	assert.Empty(t, ticket)
+	assert.Empty(t, protocol)
+
+	ticket, _ = TicketFromProtocols("")
+	assert.Empty(t, ticket)
+}
+
+func TestWebsocketTicket(t *testing.T) {
+	assert.Equal(t, "query", WebsocketTicket(
+		map[string]string{"ticket": "query"},
+		map[string]string{"Sec-WebSocket-Protocol": "ticket.header"},
+	))
+	assert.Equal(t, "header", WebsocketTicket(
+		map[string]string{"token": "jwt"},
+		map[string]string{"sec-websocket-protocol": "ticket.header"},
+	))
+	assert.Empty(t, WebsocketTicket(map[string]string{"token": "jwt"}, nil))
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// NewPrivateStorageFromEnv stores private blobs (e.g. archives of account exports, feedback screenshots) in the
+// PRIVATE_BUCKET bucket of S3, or in the LOCAL_PRIVATE_DIR directory (default .private) for local runs.
+// Neither is served publicly, blobs are only read through the rest api by users who are allowed to.
+func NewPrivateStorageFromEnv(ctx context.Context) (Storage, error) {
+	if bucket := os.Getenv("PRIVATE_BUCKET"); bucket != "" {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Storage(cfg, bucket, ""), nil
+	}
+
+	dir := os.Getenv("LOCAL_PRIVATE_DIR")
+	if dir == "" {
+		dir = ".private"
+	}
+	localStorage, err := NewLocalStorage(dir, "")
+	if err != nil {
+		return nil, err
+	}
+	return localStorage, nil
+}
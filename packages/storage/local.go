@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage stores blobs in a directory of the local disk, it is used for development
+// where the directory is served at BaseURL (e.g. by fiber static middleware)
+type LocalStorage struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalStorage(dir string, baseURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+func (s LocalStorage) Put(_ context.Context, key string, _ string, content io.Reader) (string, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return "", err
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, content); err != nil {
+		_ = os.Remove(filePath)
+		return "", err
+	}
+
+	return s.BaseURL + "/" + key, nil
+}
+
+func (s LocalStorage) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(filePath)
+}
+
+func (s LocalStorage) Delete(_ context.Context, key string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s LocalStorage) KeyOf(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, s.BaseURL+"/")
+	if !ok {
+		return "", false
+	}
+	if _, err := s.filePath(key); err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// filePath returns the path of the key in the directory, keys escaping the directory are rejected
+func (s LocalStorage) filePath(key string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocalStorage(dir, "http://localhost:8080/storage/")
+	assert.Nil(t, err)
+
+	url, err := s.Put(context.Background(), "avatars/user/avatar.png", "image/png", strings.NewReader("content"))
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/storage/avatars/user/avatar.png", url)
+	content, err := os.ReadFile(filepath.Join(dir, "avatars", "user", "avatar.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, "content", string(content))
+
+	key, ok := s.KeyOf(url)
+	assert.True(t, ok)
+	assert.Equal(t, "avatars/user/avatar.png", key)
+	reader, err := s.Open(context.Background(), key)
+	assert.Nil(t, err)
+	content, err = io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.Nil(t, reader.Close())
+	_, ok = s.KeyOf("https://example.com/avatar.png")
+	assert.False(t, ok)
+
+	assert.Nil(t, s.Delete(context.Background(), key))
+	assert.Nil(t, s.Delete(context.Background(), key))
+
+	for _, key := range []string{"", "/etc/passwd", "../avatar.png", "avatars/../../avatar.png"} {
+		_, err = s.Put(context.Background(), key, "image/png", strings.NewReader("content"))
+		assert.Equal(t, ErrInvalidKey, err, key)
+	}
+}
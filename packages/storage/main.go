@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage stores blobs (e.g. avatars of users) by keys, keys are slash separated paths
+// like "avatars/<userID>/<name>.png"
+type Storage interface {
+	// Put stores the content at the key, overwriting the existing one, and returns the URL to access it
+	Put(ctx context.Context, key string, contentType string, content io.Reader) (string, error)
+	// Open returns the content of the blob at the key, the caller must close it
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob at the key, it does nothing if the key does not exist
+	Delete(ctx context.Context, key string) error
+	// KeyOf returns the key of a blob from its URL returned by Put, false if the URL is not of the storage
+	KeyOf(url string) (string, bool)
+}
+
+// validKey rejects absolute keys and keys which are not clean or escape the root with ".."
+func validKey(key string) bool {
+	return key != "" && !path.IsAbs(key) && path.Clean(key) == key && !strings.HasPrefix(key, "..")
+}
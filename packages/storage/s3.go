@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Storage stores blobs in a S3 bucket, contents are uploaded in parts so that streams of unknown
+// length (e.g. export archives) are not buffered. URLs are of BaseURL, blobs of private buckets
+// are only read by Open.
+type S3Storage struct {
+	Client   *s3.Client
+	Uploader *manager.Uploader
+	Bucket   string
+	BaseURL  string
+}
+
+// NewS3Storage uses the virtual-hosted URL of the bucket if baseURL is empty
+func NewS3Storage(cfg aws.Config, bucket string, baseURL string) *S3Storage {
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, cfg.Region)
+	}
+	client := s3.NewFromConfig(cfg)
+
+	return &S3Storage{
+		Client:   client,
+		Uploader: manager.NewUploader(client),
+		Bucket:   bucket,
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s S3Storage) Put(ctx context.Context, key string, contentType string, content io.Reader) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	_, err := s.Uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        content,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.BaseURL + "/" + key, nil
+}
+
+func (s S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	object, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return object.Body, nil
+}
+
+// Delete does nothing if the key does not exist since S3 does not fail deletions of missing keys
+func (s S3Storage) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s S3Storage) KeyOf(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, s.BaseURL+"/")
+	if !ok || !validKey(key) {
+		return "", false
+	}
+	return key, true
+}
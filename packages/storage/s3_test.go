@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Storage(t *testing.T) {
+	s := NewS3Storage(aws.Config{Region: "ap-southeast-1"}, "exports", "")
+	assert.Equal(t, "https://exports.s3.ap-southeast-1.amazonaws.com", s.BaseURL)
+
+	key, ok := s.KeyOf("https://exports.s3.ap-southeast-1.amazonaws.com/exports/user/takeout.zip")
+	assert.True(t, ok)
+	assert.Equal(t, "exports/user/takeout.zip", key)
+	_, ok = s.KeyOf("https://exports.s3.ap-southeast-1.amazonaws.com/../takeout.zip")
+	assert.False(t, ok)
+	_, ok = s.KeyOf("https://example.com/takeout.zip")
+	assert.False(t, ok)
+
+	// invalid keys are rejected before requests to S3
+	for _, key := range []string{"", "/etc/passwd", "../takeout.zip"} {
+		_, err := s.Put(context.Background(), key, "application/zip", strings.NewReader("content"))
+		assert.Equal(t, ErrInvalidKey, err, key)
+		_, err = s.Open(context.Background(), key)
+		assert.Equal(t, ErrInvalidKey, err, key)
+	}
+}
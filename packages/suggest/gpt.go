@@ -14,6 +14,8 @@ type GPTSuggesterOptions struct {
 	nChat            int
 	nText            int
 	modelTemperature float32
+	// maximum tokens of each completion, the API default is used if it is 0
+	maxTokens int
 }
 
 var DefaultSuggesterOptions = GPTSuggesterOptions{
@@ -36,10 +38,23 @@ func (s *GPTSuggester) ChatCompletion(
 	msgs []Message,
 	prompter ...Prompter,
 ) ([]string, error) {
+	suggestion, err := s.ChatCompletionWithUsage(ctx, userData, msgs, prompter...)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return suggestion.Suggestions, nil
+}
+
+func (s *GPTSuggester) ChatCompletionWithUsage(
+	ctx context.Context,
+	userData UserData,
+	msgs []Message,
+	prompter ...Prompter,
+) (*Suggestion, error) {
 	var (
-		suggestions = []string{}
-		prompt      = ""
-		err         error
+		prompt = ""
+		err    error
 	)
 
 	switch len(prompter) {
@@ -59,7 +74,7 @@ func (s *GPTSuggester) ChatCompletion(
 	}
 
 	if err != nil {
-		return suggestions, err
+		return nil, err
 	}
 
 	req := openai.CompletionRequest{
@@ -67,21 +82,28 @@ func (s *GPTSuggester) ChatCompletion(
 		Prompt:      prompt,
 		N:           s.nChat,
 		Temperature: s.modelTemperature,
+		MaxTokens:   s.maxTokens,
 	}
 	rsp, err := s.client.CreateCompletion(ctx, req)
 	if err != nil {
-		return suggestions, err
+		return nil, err
 	}
 
 	if len(rsp.Choices) == 0 {
-		return suggestions, errors.New("gptSuggester: got empty reply from server")
+		return nil, errors.New("gptSuggester: got empty reply from server")
 	}
 
+	suggestion := &Suggestion{
+		Suggestions:    []string{},
+		RequestTokens:  rsp.Usage.PromptTokens,
+		ResponseTokens: rsp.Usage.CompletionTokens,
+		Timestamp:      rsp.Created,
+	}
 	for _, choice := range rsp.Choices {
-		suggestions = append(suggestions, choice.Text)
+		suggestion.Suggestions = append(suggestion.Suggestions, choice.Text)
 	}
 
-	return suggestions, nil
+	return suggestion, nil
 }
 
 func (s *GPTSuggester) TextCompletion(
@@ -94,6 +116,7 @@ func (s *GPTSuggester) TextCompletion(
 		Prompt:      prompt,
 		N:           s.nText,
 		Temperature: s.modelTemperature,
+		MaxTokens:   s.maxTokens,
 	}
 	rsp, err := s.client.CreateCompletion(ctx, req)
 	if err != nil {
@@ -164,6 +187,13 @@ func WithChatModel(model string) Option {
 	})
 }
 
+// WithMaxTokens bounds the tokens of each completion, the completion is truncated if it reaches the bound
+func WithMaxTokens(maxTokens int) Option {
+	return optionAdapter(func(s *GPTSuggester) {
+		s.maxTokens = maxTokens
+	})
+}
+
 func WithPrompter(prompter Prompter) Option {
 	return optionAdapter(func(s *GPTSuggester) {
 		s.prompter = prompter
@@ -13,3 +13,8 @@ type Suggester interface {
 	ChatCompletion(context.Context, UserData, []Message, ...Prompter) ([]string, error)
 	TextCompletion(context.Context, UserData, string) ([]string, error)
 }
+
+// UsageSuggester is implemented by suggesters which could report the token usage of completions
+type UsageSuggester interface {
+	ChatCompletionWithUsage(context.Context, UserData, []Message, ...Prompter) (*Suggestion, error)
+}
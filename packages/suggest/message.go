@@ -7,6 +7,10 @@ import (
 
 const (
 	DefaultTimeFormat = "02/01/2006-15:04:05"
+
+	// senders of messages in prompts which are built for a learner
+	LearnerSender = "Learner"
+	PartnerSender = "Partner"
 )
 
 type Message struct {
@@ -0,0 +1,64 @@
+package suggest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var partnerReplyEmbed = `Learner information: language: %s, level: %s
+		Recent messages: [%s]
+		Prompt:
+			You are the Partner, a friendly native speaker who helps the Learner practise %s, and you have to reply the latest message of the Learner.
+			Only use words and grammar that the Learner could understand at the Learner's level, keep the reply short,
+			and ask a question to keep the conversation going when it is suitable.
+			Just return the text.`
+
+// PartnerReplyMaxTokens bounds the completion of a partner reply, which is asked to be short
+const PartnerReplyMaxTokens = 150
+
+// PartnerReplyPrompter builds the prompt for a bot partner to reply the learner,
+// messages of the learner must be sent by LearnerSender, the others by PartnerSender
+type PartnerReplyPrompter struct {
+	embed    string
+	userData UserData
+	messages []Message
+}
+
+func (p PartnerReplyPrompter) Build() (string, error) {
+	if len(p.messages) == 0 {
+		return "", errors.New("partnerReplyPrompter: expected at least one message")
+	}
+
+	msgs := []string{}
+	for _, msg := range p.messages {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", msg.Sender, msg.Content))
+	}
+	return fmt.Sprintf(
+		p.embed,
+		p.userData.Learning.Lang,
+		p.userData.Learning.Level,
+		strings.Join(msgs, "\n"),
+		p.userData.Learning.Lang,
+	), nil
+}
+
+func (p *PartnerReplyPrompter) Update(objs ...any) error {
+	for _, obj := range objs {
+		switch doc := obj.(type) {
+		case UserData:
+			p.userData = doc
+		case []Message:
+			p.messages = doc
+		default:
+			return errors.New("partnerReplyPrompter: expected(UserData, []Message) got unknown")
+		}
+	}
+	return nil
+}
+
+func NewPartnerReplyPrompter() *PartnerReplyPrompter {
+	return &PartnerReplyPrompter{
+		embed: partnerReplyEmbed,
+	}
+}
@@ -0,0 +1,27 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPartnerReplyPrompt(t *testing.T) {
+	p := NewPartnerReplyPrompter()
+	_, err := p.Build()
+	assert.NotNil(t, err)
+
+	err = p.Update(
+		newUserContext("learner", Language{Lang: LangVi, Level: Advanced}, Language{Lang: LangEn, Level: Beginner}),
+		[]Message{
+			*NewMessage(PartnerSender, LearnerSender, "Hi, how was your weekend?"),
+			*NewMessage(LearnerSender, PartnerSender, "It was good, I go to the beach."),
+		},
+	)
+	assert.Nil(t, err)
+
+	prompt, err := p.Build()
+	assert.Nil(t, err)
+	assert.Contains(t, prompt, "language: English, level: Beginner")
+	assert.Contains(t, prompt, "Partner: Hi, how was your weekend?\nLearner: It was good, I go to the beach.")
+}
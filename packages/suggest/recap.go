@@ -0,0 +1,114 @@
+package suggest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var conversationRecapEmbed = `Learner information: language: %s, level: %s
+		Messages: [%s]
+		Prompt:
+			You are a language teacher, summarize the conversation between the learner and the partner for the learner.
+			List the topics discussed, the new vocabulary which the learner could learn from the partner's messages
+			(words or phrases above the learner's level, with their meaning in %s), and the mistakes in the learner's messages.
+			Return only a JSON object, without any other text, in the format:
+			{"topics": ["topic"], "vocabulary": [{"word": "word", "meaning": "meaning"}], "mistakes": [{"original": "original text", "corrected": "corrected text", "explanation": "explanation"}]}`
+
+// RecapMaxTokens bounds the completion of a recap, which must be large enough for the whole JSON object
+const RecapMaxTokens = 1024
+
+type RecapVocabulary struct {
+	Word    string `json:"word"`
+	Meaning string `json:"meaning"`
+}
+
+type RecapMistake struct {
+	Original    string `json:"original"`
+	Corrected   string `json:"corrected"`
+	Explanation string `json:"explanation"`
+}
+
+type ConversationRecap struct {
+	Topics     []string          `json:"topics"`
+	Vocabulary []RecapVocabulary `json:"vocabulary"`
+	Mistakes   []RecapMistake    `json:"mistakes"`
+}
+
+// ConversationRecapPrompter builds the prompt to recap a conversation of the learner,
+// messages of the learner must be sent by LearnerSender, the others by PartnerSender
+type ConversationRecapPrompter struct {
+	embed    string
+	userData UserData
+	messages []Message
+}
+
+func (p ConversationRecapPrompter) Build() (string, error) {
+	if len(p.messages) == 0 {
+		return "", errors.New("conversationRecapPrompter: expected at least one message")
+	}
+
+	msgs := []string{}
+	for _, msg := range p.messages {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", msg.Sender, msg.Content))
+	}
+	return fmt.Sprintf(
+		p.embed,
+		p.userData.Learning.Lang,
+		p.userData.Learning.Level,
+		strings.Join(msgs, "\n"),
+		p.userData.Native.Lang,
+	), nil
+}
+
+func (p *ConversationRecapPrompter) Update(objs ...any) error {
+	for _, obj := range objs {
+		switch doc := obj.(type) {
+		case UserData:
+			p.userData = doc
+		case []Message:
+			p.messages = doc
+		default:
+			return errors.New("conversationRecapPrompter: expected(UserData, []Message) got unknown")
+		}
+	}
+	return nil
+}
+
+func NewConversationRecapPrompter() *ConversationRecapPrompter {
+	return &ConversationRecapPrompter{
+		embed: conversationRecapEmbed,
+	}
+}
+
+// ParseConversationRecap parses the completion of ConversationRecapPrompter,
+// text around the JSON object (e.g. markdown code fence) is ignored.
+func ParseConversationRecap(completion string) (*ConversationRecap, error) {
+	start, end := strings.Index(completion, "{"), strings.LastIndex(completion, "}")
+	if start == -1 || end < start {
+		return nil, errors.New("conversationRecap: completion does not contain json object")
+	}
+
+	recap := &ConversationRecap{}
+	if err := json.Unmarshal([]byte(completion[start:end+1]), recap); err != nil {
+		return nil, fmt.Errorf("conversationRecap: cannot parse completion: %v", err)
+	}
+
+	if recap.Topics == nil {
+		recap.Topics = []string{}
+	}
+	vocabulary := make([]RecapVocabulary, 0, len(recap.Vocabulary))
+	for _, v := range recap.Vocabulary {
+		v.Word, v.Meaning = strings.TrimSpace(v.Word), strings.TrimSpace(v.Meaning)
+		if v.Word != "" {
+			vocabulary = append(vocabulary, v)
+		}
+	}
+	recap.Vocabulary = vocabulary
+	if recap.Mistakes == nil {
+		recap.Mistakes = []RecapMistake{}
+	}
+
+	return recap, nil
+}
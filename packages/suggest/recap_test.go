@@ -0,0 +1,49 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildConversationRecapPrompt(t *testing.T) {
+	p := NewConversationRecapPrompter()
+	_, err := p.Build()
+	assert.NotNil(t, err)
+
+	err = p.Update(
+		newUserContext("learner", Language{Lang: LangVi, Level: Advanced}, Language{Lang: LangEn, Level: Beginner}),
+		[]Message{
+			*NewMessage(PartnerSender, LearnerSender, "The weather is scorching today."),
+			*NewMessage(LearnerSender, PartnerSender, "Yes, I am go to the beach."),
+		},
+	)
+	assert.Nil(t, err)
+
+	prompt, err := p.Build()
+	assert.Nil(t, err)
+	assert.Contains(t, prompt, "Partner: The weather is scorching today.\nLearner: Yes, I am go to the beach.")
+	assert.Contains(t, prompt, "meaning in Vietnamese")
+
+	assert.NotNil(t, p.Update("unknown"))
+}
+
+func TestParseConversationRecap(t *testing.T) {
+	recap, err := ParseConversationRecap("```json\n" + `{
+		"topics": ["weather"],
+		"vocabulary": [{"word": " scorching ", "meaning": "very hot"}, {"word": "", "meaning": "empty"}],
+		"mistakes": [{"original": "I am go", "corrected": "I am going", "explanation": "present continuous"}]
+	}` + "\n```")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"weather"}, recap.Topics)
+	assert.Equal(t, []RecapVocabulary{{Word: "scorching", Meaning: "very hot"}}, recap.Vocabulary)
+	assert.Equal(t, 1, len(recap.Mistakes))
+
+	recap, err = ParseConversationRecap(`{"topics": ["greeting"]}`)
+	assert.Nil(t, err)
+	assert.NotNil(t, recap.Vocabulary)
+	assert.NotNil(t, recap.Mistakes)
+
+	_, err = ParseConversationRecap("no recap")
+	assert.NotNil(t, err)
+}
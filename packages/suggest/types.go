@@ -3,6 +3,9 @@ package suggest
 import (
 	"fmt"
 	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
 )
 
 type Suggestion struct {
@@ -39,6 +42,35 @@ func GetUserData(userID string) (UserData, error) {
 	}, nil
 }
 
+// NewUserData constructs the user data of the user's languages, which are language codes with
+// RFC-5646 format. The first learning language is used, and the learner is considered a beginner
+// of it since levels are not collected.
+func NewUserData(userID string, native string, learnings []string) (UserData, error) {
+	if native == "" || len(learnings) == 0 {
+		return UserData{}, fmt.Errorf("user does not have native and learning languages")
+	}
+
+	return UserData{
+		UserID:   userID,
+		Native:   Language{Lang: LangOf(native), Level: Advanced},
+		Learning: Language{Lang: LangOf(learnings[0]), Level: Beginner},
+	}, nil
+}
+
+// LangOf gets the language of the RFC-5646 code, the code is used as the name if it is unknown
+func LangOf(code string) Lang {
+	tag, err := language.Parse(code)
+	if err != nil {
+		return Lang{Code: code, Name: code}
+	}
+	base, _ := tag.Base()
+	name := display.English.Languages().Name(base)
+	if name == "" {
+		name = code
+	}
+	return Lang{Code: base.String(), Name: name}
+}
+
 const (
 	Beginner     Level = "Beginner"
 	Intermediate Level = "Intermediate"
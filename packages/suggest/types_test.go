@@ -0,0 +1,20 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserData(t *testing.T) {
+	_, err := NewUserData("learner", "vi", nil)
+	assert.NotNil(t, err)
+
+	userData, err := NewUserData("learner", "vi", []string{"en-US", "ja"})
+	assert.Nil(t, err)
+	assert.Equal(t, Language{Lang: LangVi, Level: Advanced}, userData.Native)
+	assert.Equal(t, Language{Lang: LangEn, Level: Beginner}, userData.Learning)
+
+	assert.Equal(t, Lang{Code: "ja", Name: "Japanese"}, LangOf("ja"))
+	assert.Equal(t, Lang{Code: "??", Name: "??"}, LangOf("??"))
+}
@@ -25,7 +25,8 @@ type (
  * Transport interface of user/friends service
  */
 const (
-	AddFriend EventType = "ADD_FRIEND"
+	AddFriend    EventType = "ADD_FRIEND"
+	RemoveFriend EventType = "REMOVE_FRIEND"
 )
 
 type AddFriendAction string
@@ -34,6 +35,8 @@ const (
 	InitFriendRequest   AddFriendAction = "INIT_FRIEND_REQUEST"
 	AcceptFriendRequest AddFriendAction = "ACCEPT_FRIEND_REQUEST"
 	DenyFriendRequest   AddFriendAction = "DENY_FRIEND_REQUEST"
+	// the request is withdrawn by the sender
+	CancelFriendRequest AddFriendAction = "CANCEL_FRIEND_REQUEST"
 )
 
 type AddFriendEvent struct {
@@ -47,6 +50,16 @@ type AddFriendPayload struct {
 	AddFriendRequestID string `json:"addFriendRequestId"`
 }
 
+type RemoveFriendEvent struct {
+	Event   `json:",inline"`
+	Payload RemoveFriendPayload `json:"payload"`
+}
+
+type RemoveFriendPayload struct {
+	UserID   string `json:"userId"`   // notified user
+	FriendID string `json:"friendId"` // user who removed the friendship
+}
+
 /*
  * Transport interface of collecting service
  */
@@ -64,3 +77,39 @@ type AddExplainLogEvent struct {
 	Event   `json:",inline"`
 	Payload collectingdb.ExplainLog `json:"payload"`
 }
+
+/*
+ * Transport interface of chat service
+ */
+const (
+	MentionUser EventType = "MENTION_USER"
+)
+
+type MentionUserEvent struct {
+	Event   `json:",inline"`
+	Payload MentionUserPayload `json:"payload"`
+}
+
+type MentionUserPayload struct {
+	UserID         string `json:"userId"` // mentioned user
+	SenderID       string `json:"senderId"`
+	ConversationID string `json:"conversationId"`
+	MessageID      string `json:"messageId"`
+	Content        string `json:"content"`
+}
+
+/*
+ * Transport interface of account service
+ */
+const (
+	RunAccountJob EventType = "RUN_ACCOUNT_JOB"
+)
+
+type RunAccountJobEvent struct {
+	Event   `json:",inline"`
+	Payload RunAccountJobPayload `json:"payload"`
+}
+
+type RunAccountJobPayload struct {
+	JobID string `json:"jobId"`
+}
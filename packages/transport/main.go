@@ -20,6 +20,7 @@ const (
 	CollectingGet  Key = "collecting-get"
 	Suggest        Key = "suggest"
 	Embed          Key = "embed"
+	Account        Key = "account"
 )
 
 type ConsumerMap map[Key]string
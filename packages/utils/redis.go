@@ -8,12 +8,18 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-func NewRedisClientFromEnv(ctx context.Context) *redis.Client {
-	redisClient := redis.NewClient(&redis.Options{
+// RedisOptionsFromEnv reads options of the redis client from REDIS_HOST, REDIS_PORT,
+// REDIS_USERNAME and REDIS_PASSWORD
+func RedisOptionsFromEnv() *redis.Options {
+	return &redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT")),
 		Username: os.Getenv("REDIS_USERNAME"),
 		Password: os.Getenv("REDIS_PASSWORD"),
-	})
+	}
+}
+
+func NewRedisClientFromEnv(ctx context.Context) *redis.Client {
+	redisClient := redis.NewClient(RedisOptionsFromEnv())
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		panic(err)
 	}
@@ -16,10 +16,10 @@ if [[ "$1" != "dev" && "$1" != "staging" && "$1" != "prod" ]]; then
 fi
 
 rm -rf dist/connect*$1 dist/translate*$1 dist/authorizer*$1 \
-	dist/explore*$1 dist/disconnect*$1 dist/wschat*$1$1 \
+	dist/explore*$1 dist/disconnect*$1 dist/wschat*$1$1 dist/wsscheduler*$1 \
 	dist/rest*$1 dist/notification*$1 dist/ws_authorizer*$1 \
 	dist/collecting-get*$1 dist/collecting-push*$1 \
-	dist/gosuggest*$1 dist/goembedder*$1
+	dist/gosuggest*$1 dist/goembedder*$1 dist/account*$1
 
 echo "cleaned previous build artifacts"
 
@@ -57,6 +57,12 @@ cd ./dist/wschat-$1
 zip -r ../wschat-$1.zip .
 cd ../..
 
+GOOS=linux GOARCH=arm64 CGO_ENABLED=0 GOFLAGS=-trimpath go build -mod=readonly -ldflags='-s -w' -o ./dist/wsscheduler-$1/bootstrap ./functions/websocket/scheduler
+echo "build websocket scheduler lambda function completed"
+cd ./dist/wsscheduler-$1
+zip -r ../wsscheduler-$1.zip .
+cd ../..
+
 # migrate to arm64 for better price-performance
 GOOS=linux GOARCH=arm64 CGO_ENABLED=0 GOFLAGS=-trimpath go build -tags lambda.norpc -mod=readonly -ldflags='-s -w' -o ./dist/rest-$1/bootstrap ./functions/rest
 echo "build rest api lambda function completed"
@@ -96,6 +102,12 @@ cd ./dist/collecting-get-$1
 zip -r ../collecting-get-$1.zip .
 cd ../..
 
+GOOS=linux GOARCH=arm64 CGO_ENABLED=0 GOFLAGS=-trimpath go build -tags lambda.norpc -mod=readonly -ldflags='-s -w' -o ./dist/account-$1/bootstrap ./functions/account
+echo "build account lambda function completed"
+cd ./dist/account-$1
+zip -r ../account-$1.zip .
+cd ../..
+
 GOOS=linux GOARCH=arm64 CGO_ENABLED=0 GOFLAGS=-trimpath go build -mod=readonly -ldflags='-s -w' -o ./dist/practice-$1/bootstrap ./functions/practice
 echo "build practice lambda function completed"
 cp ./firebase.admin.$1.json ./dist/practice-$1/firebase.admin.json
@@ -74,7 +74,12 @@ func (s *Service) HandleGetMatchingProfile(ctx *fiber.Ctx) error {
 		log.Println("cannot get matching profile", err)
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot get matching profile"})
 	}
-	return ctx.Status(fiber.StatusOK).JSON(profile)
+	profiles, err := s.Core.GetPublicProfiles(*profile)
+	if err != nil || len(profiles) == 0 {
+		log.Println("cannot get public profile", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot get matching profile"})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(profiles[0])
 }
 
 // HandleGetMatches returns 5 users that similarity with current user.
@@ -92,7 +97,7 @@ func (s *Service) HandleGetMatches(ctx *fiber.Ctx) error {
 		goto returnRandomPool
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(candidates)
+	return s.sendPublicProfiles(ctx, candidates)
 
 returnRandomPool:
 	pool, err := s.Core.SuggestRandom(userOID)
@@ -101,7 +106,18 @@ returnRandomPool:
 		return ctx.Status(fiber.StatusBadRequest).
 			JSON(fiber.Map{"error": "cannot suggest users"})
 	}
-	return ctx.Status(fiber.StatusOK).JSON(pool)
+	return s.sendPublicProfiles(ctx, pool)
+}
+
+// sendPublicProfiles serializes matching information of other users with their public projections
+func (s *Service) sendPublicProfiles(ctx *fiber.Ctx, infos []matchingdb.MatchInfo) error {
+	profiles, err := s.Core.GetPublicProfiles(infos...)
+	if err != nil {
+		log.Println("cannot get public profiles", err)
+		return ctx.Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"error": "cannot suggest users"})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(profiles)
 }
 
 func (s *Service) HandleAddMatchingProfile(ctx *fiber.Ctx) error {
@@ -34,7 +34,8 @@ func (m *Manager) InitRoute() {
 		return c.SendString("pong")
 	})
 
-	authorizedRoute := exploreRoute.Group("/", auth.FiberAuthMiddleware(m.Auth, m.UsersRepo))
+	users := auth.NewCachedUsers(m.UsersRepo, auth.NewUserCacheFromEnv())
+	authorizedRoute := exploreRoute.Group("/", auth.FiberAuthMiddleware(m.Auth, users))
 	authorizedRoute.Get("/suggest", m.Service.HandleGetMatches)
 	authorizedRoute.Get("/profiles/:id", m.Service.HandleGetMatchingProfile)
 	authorizedRoute.Post("/profiles", m.Service.HandleAddMatchingProfile)
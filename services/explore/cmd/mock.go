@@ -80,8 +80,7 @@ func init() {
 	core := explore.NewExplorer(matchingRepo, usersRepo, redisClient)
 	service := exploreapi.NewService(core, redisClient, tp)
 
-	adminJSON, _ := utils.GetFile("firebase.admin.json")
-	auth, err := auth.NewFirebaseManager(adminJSON)
+	auth, err := auth.NewManagerFromEnv("firebase.admin.json")
 	if err != nil {
 		panic(err)
 	}
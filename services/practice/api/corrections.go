@@ -0,0 +1,84 @@
+package practiceapi
+
+import (
+	"log"
+
+	"blinders/packages/auth"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/practicedb"
+	"blinders/packages/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SaveCorrectionBody struct {
+	MessageID string `json:"messageId"`
+}
+
+// HandleSaveCorrectionToFlashcard saves a correction message of a partner to the correction
+// collection of the corrected user, the collection is created at the first save.
+func (s Service) HandleSaveCorrectionToFlashcard(ctx *fiber.Ctx) error {
+	userAuth, ok := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	if !ok {
+		log.Fatalln("cannot get user auth information")
+	}
+	userID, _ := primitive.ObjectIDFromHex(userAuth.ID)
+
+	body, err := utils.ParseJSON[SaveCorrectionBody](ctx.Body())
+	if err != nil {
+		log.Println("invalid request body:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	messageID, err := primitive.ObjectIDFromHex(body.MessageID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid message id"})
+	}
+
+	message, err := s.MessagesRepo.GetMessageByID(messageID)
+	if err != nil {
+		log.Println("cannot get message:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot get message"})
+	}
+	if message.Type != chatdb.CorrectionMessage || message.Correction == nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "message is not a correction"})
+	}
+	if message.Correction.CorrectedUserID != userID {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "only the corrected user can save this correction"})
+	}
+
+	var collection *practicedb.FlashcardCollection
+	collections, err := s.FlashcardRepo.GetCollectionByType(userID, practicedb.CorrectionCollectionType)
+	if err != nil || len(collections) == 0 {
+		flashcards := make([]*practicedb.Flashcard, 0)
+		collection, err = s.FlashcardRepo.InsertRaw(&practicedb.FlashcardCollection{
+			Type:        practicedb.CorrectionCollectionType,
+			Name:        "Corrections",
+			Description: "Corrections from your partners",
+			UserID:      userID,
+			FlashCards:  &flashcards,
+		})
+		if err != nil {
+			log.Println("cannot create correction flashcard collection:", err)
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot create correction flashcard collection"})
+		}
+	} else {
+		collection = collections[0]
+	}
+
+	flashcard, err := s.FlashcardRepo.AddFlashcardToCollection(collection.ID, &practicedb.Flashcard{
+		Type:      practicedb.CorrectionFlashcardType,
+		FrontText: message.Correction.Original,
+		BackText:  message.Correction.Corrected,
+		Metadata:  &practicedb.CorrectionFlashcardMetadata{MessageID: message.ID},
+	})
+	if err != nil {
+		log.Println("cannot add flashcard to collection:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot add flashcard to collection"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"collectionId": collection.ID,
+		"flashcard":    flashcard,
+	})
+}
@@ -2,37 +2,52 @@ package practiceapi
 
 import (
 	"blinders/packages/auth"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/matchingdb"
 	"blinders/packages/db/practicedb"
 	"blinders/packages/db/usersdb"
+	"blinders/packages/suggest"
 	"blinders/packages/transport"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Service struct {
-	App           *fiber.App
-	Auth          auth.Manager
-	UserRepo      *usersdb.UsersRepo
-	Transport     transport.Transport
-	FlashcardRepo *practicedb.FlashcardsRepo
-	SnapshotRepo  *practicedb.SnapshotsRepo
+	App               *fiber.App
+	Auth              auth.Manager
+	UserRepo          *usersdb.UsersRepo
+	MatchingRepo      *matchingdb.MatchingRepo
+	Transport         transport.Transport
+	FlashcardRepo     *practicedb.FlashcardsRepo
+	SnapshotRepo      *practicedb.SnapshotsRepo
+	MessagesRepo      *chatdb.MessagesRepo
+	ConversationsRepo *chatdb.ConversationsRepo
+	Suggester         suggest.Suggester
 }
 
 func NewService(
 	app *fiber.App,
 	auth auth.Manager,
 	usersRepo *usersdb.UsersRepo,
+	matchingRepo *matchingdb.MatchingRepo,
 	flashcardsRepo *practicedb.FlashcardsRepo,
 	snapshotRepo *practicedb.SnapshotsRepo,
+	messagesRepo *chatdb.MessagesRepo,
+	conversationsRepo *chatdb.ConversationsRepo,
+	suggester suggest.Suggester,
 	transport transport.Transport,
 ) *Service {
 	return &Service{
-		App:           app,
-		Auth:          auth,
-		UserRepo:      usersRepo,
-		FlashcardRepo: flashcardsRepo,
-		SnapshotRepo:  snapshotRepo,
-		Transport:     transport,
+		App:               app,
+		Auth:              auth,
+		UserRepo:          usersRepo,
+		MatchingRepo:      matchingRepo,
+		FlashcardRepo:     flashcardsRepo,
+		SnapshotRepo:      snapshotRepo,
+		MessagesRepo:      messagesRepo,
+		Transport:         transport,
+		ConversationsRepo: conversationsRepo,
+		Suggester:         suggester,
 	}
 }
 
@@ -42,11 +57,13 @@ func (s *Service) InitRoute() {
 		return c.SendString("hello from practice service")
 	})
 
-	authorized := practiceRoute.Group("/", auth.FiberAuthMiddleware(s.Auth, s.UserRepo))
+	users := auth.NewCachedUsers(s.UserRepo, auth.NewUserCacheFromEnv())
+	authorized := practiceRoute.Group("/", auth.FiberAuthMiddleware(s.Auth, users))
 	authorized.Get("/random-review", s.HandleGetRandomReview)
 	authorized.Get("/fast-review", s.HandleGetFastReviewFromExplainLog)
 
 	flashcards := authorized.Group("/flashcards")
+	flashcards.Post("/corrections", s.HandleSaveCorrectionToFlashcard)
 	flashcardCollections := flashcards.Group("/collections")
 
 	flashcardCollections.Get("/", s.HandleGetFlashcardCollections)
@@ -64,6 +81,10 @@ func (s *Service) InitRoute() {
 	validatedCollections.Put("/:flashcardId", s.HandleUpdateFlashcardInCollection)
 	validatedCollections.Delete("/:flashcardId", s.HandleRemoveFlashcardFromCollection)
 
+	recaps := authorized.Group("/recaps")
+	recaps.Post("/", s.HandleCreateRecap)
+	recaps.Post("/flashcards", s.HandleSaveRecapVocabularyToFlashcards)
+
 	explainLog := authorized.Group("/explain-log")
 	explainLog.Get("/", s.HandleFetchExplainMetadata)
 	explainLog.Get("/flashcard", s.HandleCreateFlashcardFromExplainLog)
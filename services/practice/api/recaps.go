@@ -0,0 +1,222 @@
+package practiceapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"blinders/packages/auth"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/practicedb"
+	"blinders/packages/suggest"
+	"blinders/packages/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultRecapWindow     = time.Hour * 24
+	MaxRecapWindow         = time.Hour * 24 * 7
+	MaxRecapMessages       = 200
+	MaxRecapFlashcards     = 50
+	recapCompletionTimeout = time.Second * 30
+)
+
+type CreateRecapBody struct {
+	ConversationID string     `json:"conversationId"`
+	From           *time.Time `json:"from"`
+	To             *time.Time `json:"to"`
+}
+
+type RecapResponse struct {
+	suggest.ConversationRecap
+	ConversationID primitive.ObjectID `json:"conversationId"`
+	From           time.Time          `json:"from"`
+	To             time.Time          `json:"to"`
+	MessageCount   int                `json:"messageCount"`
+}
+
+// HandleCreateRecap recaps messages of the conversation in the time window, which is
+// the last 24 hours by default: topics, new vocabulary from partner's messages and the user's mistakes
+func (s Service) HandleCreateRecap(ctx *fiber.Ctx) error {
+	if s.Suggester == nil {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "recap is not available"})
+	}
+
+	userAuth, ok := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	if !ok {
+		log.Fatalln("cannot get user auth information")
+	}
+	userID, _ := primitive.ObjectIDFromHex(userAuth.ID)
+
+	body, err := utils.ParseJSON[CreateRecapBody](ctx.Body())
+	if err != nil {
+		log.Println("invalid request body:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	to := time.Now()
+	if body.To != nil {
+		to = *body.To
+	}
+	from := to.Add(-DefaultRecapWindow)
+	if body.From != nil {
+		from = *body.From
+	}
+	if !from.Before(to) || to.Sub(from) > MaxRecapWindow {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("time window must be positive and at most %s", MaxRecapWindow),
+		})
+	}
+
+	conversation, err := s.getConversationOfUser(body.ConversationID, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	messages, err := s.MessagesRepo.GetMessagesOfConversationInTimeRange(conversation.ID, from, to, MaxRecapMessages)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot get messages"})
+	}
+	if len(messages) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no message in the time window"})
+	}
+
+	msgs := make([]suggest.Message, 0, len(messages))
+	for _, m := range messages {
+		if m.Content == "" {
+			continue
+		}
+		sender, receiver := suggest.PartnerSender, suggest.LearnerSender
+		if m.SenderID == userID {
+			sender, receiver = receiver, sender
+		}
+		msgs = append(msgs, suggest.Message{
+			Sender:    sender,
+			Receiver:  receiver,
+			Content:   m.Content,
+			Timestamp: m.CreatedAt.Time().Unix(),
+		})
+	}
+
+	matchInfo, err := s.MatchingRepo.GetByUserID(userID)
+	if err != nil {
+		log.Println("cannot get match info:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot get languages of user"})
+	}
+	userData, err := suggest.NewUserData(userAuth.ID, matchInfo.Native, matchInfo.Learnings)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	prompter := suggest.NewConversationRecapPrompter()
+	if err := prompter.Update(userData, msgs); err != nil {
+		log.Println("cannot update recap prompter:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot create recap"})
+	}
+	prompt, err := prompter.Build()
+	if err != nil {
+		log.Println("cannot build recap prompt:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot create recap"})
+	}
+
+	suggestCtx, cancel := context.WithTimeout(ctx.UserContext(), recapCompletionTimeout)
+	defer cancel()
+	completions, err := s.Suggester.TextCompletion(suggestCtx, userData, prompt)
+	if err != nil || len(completions) == 0 {
+		log.Println("cannot complete recap prompt:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot create recap"})
+	}
+	recap, err := suggest.ParseConversationRecap(completions[0])
+	if err != nil {
+		log.Println("cannot parse recap:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot create recap"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(RecapResponse{
+		ConversationRecap: *recap,
+		ConversationID:    conversation.ID,
+		From:              from,
+		To:                to,
+		MessageCount:      len(msgs),
+	})
+}
+
+type SaveRecapVocabularyBody struct {
+	ConversationID string                    `json:"conversationId"`
+	Vocabulary     []suggest.RecapVocabulary `json:"vocabulary"`
+}
+
+// HandleSaveRecapVocabularyToFlashcards creates a flashcard collection from the vocabulary of a recap
+func (s Service) HandleSaveRecapVocabularyToFlashcards(ctx *fiber.Ctx) error {
+	userAuth, ok := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	if !ok {
+		log.Fatalln("cannot get user auth information")
+	}
+	userID, _ := primitive.ObjectIDFromHex(userAuth.ID)
+
+	body, err := utils.ParseJSON[SaveRecapVocabularyBody](ctx.Body())
+	if err != nil {
+		log.Println("invalid request body:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	conversation, err := s.getConversationOfUser(body.ConversationID, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	flashcards := make([]*practicedb.Flashcard, 0, len(body.Vocabulary))
+	for _, v := range body.Vocabulary {
+		word, meaning := strings.TrimSpace(v.Word), strings.TrimSpace(v.Meaning)
+		if word == "" || meaning == "" {
+			continue
+		}
+		flashcards = append(flashcards, &practicedb.Flashcard{
+			Type:      practicedb.RecapFlashcardType,
+			FrontText: word,
+			BackText:  meaning,
+			Metadata:  &practicedb.RecapFlashcardMetadata{ConversationID: conversation.ID},
+		})
+	}
+	if len(flashcards) == 0 || len(flashcards) > MaxRecapFlashcards {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("vocabulary must have from 1 to %d words with meaning", MaxRecapFlashcards),
+		})
+	}
+
+	collection, err := s.FlashcardRepo.InsertRaw(&practicedb.FlashcardCollection{
+		Type:        practicedb.RecapCollectionType,
+		Name:        fmt.Sprintf("Recap %s", time.Now().Format("02/01/2006")),
+		Description: "Vocabulary from your conversation recap",
+		UserID:      userID,
+		FlashCards:  &flashcards,
+	})
+	if err != nil {
+		log.Println("cannot insert flashcard collection:", err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot insert flashcard collection"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(collection)
+}
+
+func (s Service) getConversationOfUser(rawConversationID string, userID primitive.ObjectID) (*chatdb.Conversation, error) {
+	conversationID, err := primitive.ObjectIDFromHex(rawConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid conversation id")
+	}
+
+	conversation, err := s.ConversationsRepo.GetConversationByID(conversationID)
+	if err != nil {
+		log.Println("cannot get conversation:", err)
+		return nil, fmt.Errorf("cannot get conversation")
+	}
+	if !slices.ContainsFunc(conversation.Members, func(m chatdb.Member) bool { return m.UserID == userID }) {
+		return nil, fmt.Errorf("user is not a member of conversation")
+	}
+
+	return conversation, nil
+}
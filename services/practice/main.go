@@ -6,16 +6,19 @@ import (
 	"os"
 
 	"blinders/packages/auth"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/matchingdb"
 	"blinders/packages/db/practicedb"
 	"blinders/packages/db/usersdb"
 	dbutils "blinders/packages/db/utils"
+	"blinders/packages/suggest"
 	"blinders/packages/transport"
-	"blinders/packages/utils"
 	practiceapi "blinders/services/practice/api"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/joho/godotenv"
+	"github.com/sashabaranov/go-openai"
 )
 
 var service *practiceapi.Service
@@ -40,8 +43,10 @@ func init() {
 	usersRepo := usersdb.NewUsersRepo(db)
 	snapshotRepo := practicedb.NewSnapshotsRepo(db)
 
-	adminJSON, _ := utils.GetFile("firebase.admin.json")
-	auth, _ := auth.NewFirebaseManager(adminJSON)
+	auth, err := auth.NewManagerFromEnv("firebase.admin.json")
+	if err != nil {
+		log.Fatal(err)
+	}
 	flashcardsRepo := practicedb.NewFlashcardsRepo(db)
 	transportConsumers := transport.ConsumerMap{
 		transport.Suggest: fmt.Sprintf(
@@ -58,13 +63,26 @@ func init() {
 		),
 	}
 	transport := transport.NewLocalTransportWithConsumers(transportConsumers)
+
+	// recap is disabled if openai api key is not provided
+	var suggester suggest.Suggester
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		suggester, _ = suggest.NewGPTSuggester(
+			openai.NewClient(apiKey),
+			suggest.WithMaxTokens(suggest.RecapMaxTokens),
+		)
+	}
 	app := fiber.New()
 	service = practiceapi.NewService(
 		app,
 		auth,
 		usersRepo,
+		matchingdb.NewMatchingRepo(db),
 		flashcardsRepo,
 		snapshotRepo,
+		chatdb.NewMessagesRepo(db),
+		chatdb.NewConversationsRepo(db),
+		suggester,
 		transport,
 	)
 
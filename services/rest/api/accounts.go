@@ -0,0 +1,149 @@
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"blinders/packages/auth"
+	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
+	"blinders/packages/transport"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AccountsService struct {
+	UsersRepo       *usersdb.UsersRepo
+	AccountJobsRepo *usersdb.AccountJobsRepo
+	// Storage is the private storage of export archives, which must not be served publicly since archives
+	// are downloaded by DownloadExport. Exports are not available if it is nil.
+	Storage     storage.Storage
+	Transporter transport.Transport
+	ConsumerMap transport.ConsumerMap
+}
+
+func NewAccountsService(
+	usersRepo *usersdb.UsersRepo,
+	accountJobsRepo *usersdb.AccountJobsRepo,
+	exportStorage storage.Storage,
+	transporter transport.Transport,
+	consumerMap transport.ConsumerMap,
+) *AccountsService {
+	return &AccountsService{
+		UsersRepo:       usersRepo,
+		AccountJobsRepo: accountJobsRepo,
+		Storage:         exportStorage,
+		Transporter:     transporter,
+		ConsumerMap:     consumerMap,
+	}
+}
+
+// DeleteSelf requests deletion of the account of the user, the deletion is run asynchronously by the account
+// service. A deletion job which is not completed is resumed instead of creating a new one.
+func (s AccountsService) DeleteSelf(ctx *fiber.Ctx) error {
+	return s.requestAccountJob(ctx, usersdb.AccountDeletionJob)
+}
+
+// ExportSelf requests an export of all data of the user, the archive is downloaded by DownloadExport
+// after the job is completed
+func (s AccountsService) ExportSelf(ctx *fiber.Ctx) error {
+	if s.Storage == nil {
+		return ctx.Status(http.StatusServiceUnavailable).JSON(&fiber.Map{"error": "export is not available"})
+	}
+
+	return s.requestAccountJob(ctx, usersdb.AccountExportJob)
+}
+
+func (s AccountsService) requestAccountJob(ctx *fiber.Ctx, jobType usersdb.AccountJobType) error {
+	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	user, err := s.UsersRepo.GetUserByFirebaseUID(userAuth.AuthID)
+	if err == mongo.ErrNoDocuments {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{"error": "user not found"})
+	} else if err != nil {
+		log.Println("can not get user:", err)
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{"error": "can not get user"})
+	}
+
+	job, err := s.AccountJobsRepo.GetOrInsertAccountJob(user, jobType)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{"error": "can not create job"})
+	}
+	if err := s.pushRunAccountJobEvent(job.ID); err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{"error": "can not run job"})
+	}
+
+	return ctx.Status(http.StatusAccepted).JSON(job)
+}
+
+// GetAccountJob returns the job with per-step progress, the job is still available after the user is deleted
+func (s AccountsService) GetAccountJob(ctx *fiber.Ctx) error {
+	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	jobID, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid job id"})
+	}
+
+	job, err := s.AccountJobsRepo.GetAccountJobByID(jobID)
+	if err != nil || job.AuthID != userAuth.AuthID {
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Println("can not get account job:", err)
+		}
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{"error": "job not found"})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(job)
+}
+
+// DownloadExport streams the archive of the completed export job, only the user of the job could download it
+func (s AccountsService) DownloadExport(ctx *fiber.Ctx) error {
+	if s.Storage == nil {
+		return ctx.Status(http.StatusServiceUnavailable).JSON(&fiber.Map{"error": "export is not available"})
+	}
+
+	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	jobID, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid job id"})
+	}
+
+	job, err := s.AccountJobsRepo.GetAccountJobByID(jobID)
+	if err != nil || job.AuthID != userAuth.AuthID || job.Type != usersdb.AccountExportJob {
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Println("can not get account job:", err)
+		}
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{"error": "job not found"})
+	}
+	if job.Status != usersdb.AccountJobCompleted || job.Result == "" {
+		return ctx.Status(http.StatusConflict).JSON(&fiber.Map{"error": "export is not completed"})
+	}
+
+	archive, err := s.Storage.Open(ctx.UserContext(), job.Result)
+	if err != nil {
+		log.Println("can not open export archive:", err)
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{"error": "archive not found"})
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="takeout-%s.zip"`, job.ID.Hex()))
+	// the archive is closed by fasthttp after it is sent
+	return ctx.SendStream(archive)
+}
+
+func (s AccountsService) pushRunAccountJobEvent(jobID primitive.ObjectID) error {
+	event := transport.RunAccountJobEvent{
+		Event:   transport.Event{Type: transport.RunAccountJob},
+		Payload: transport.RunAccountJobPayload{JobID: jobID.Hex()},
+	}
+	payload, _ := json.Marshal(event)
+	err := s.Transporter.Push(context.Background(), s.ConsumerMap[transport.Account], payload)
+	if err != nil {
+		log.Println("failed to push account job event:", err)
+	}
+
+	return err
+}
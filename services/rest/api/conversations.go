@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"blinders/packages/auth"
@@ -175,6 +176,42 @@ func (s ConversationsService) CreateNewIndividualConversation(ctx *fiber.Ctx) er
 	return nil
 }
 
+// GetOrCreatePartnerBotConversation returns the individual conversation of the user with the partner bot,
+// the conversation is created at the first call. The bot does not need to be a friend of the user.
+func (s ConversationsService) GetOrCreatePartnerBotConversation(ctx *fiber.Ctx) error {
+	authUser := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(authUser.ID)
+
+	bot, err := s.UsersRepo.GetOrInsertPartnerBot()
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	conversations, err := s.ConversationsRepo.GetConversationByMembers(
+		[]primitive.ObjectID{userID, bot.ID},
+		chatdb.IndividualConversation)
+	if err != nil {
+		log.Println("can not get conversations:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get conversations",
+		})
+	}
+	if len(*conversations) != 0 {
+		return ctx.Status(http.StatusOK).JSON((*conversations)[0])
+	}
+
+	conv, err := s.ConversationsRepo.InsertIndividualConversation(userID, bot.ID)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(conv)
+}
+
 func (s ConversationsService) CheckFriendRelationship(
 	userID primitive.ObjectID,
 	friendID primitive.ObjectID,
@@ -225,3 +262,91 @@ func (s ConversationsService) GetMessagesOfConversation(ctx *fiber.Ctx) error {
 
 	return ctx.Status(http.StatusOK).JSON(messages)
 }
+
+type MuteConversationDTO struct {
+	Muted bool `json:"muted"`
+}
+
+// MuteConversation mutes or unmutes the conversation for the current user,
+// members are still notified when they are mentioned in a muted conversation
+func (s ConversationsService) MuteConversation(ctx *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid id",
+		})
+	}
+
+	dto, err := utils.ParseJSON[MuteConversationDTO](ctx.Body())
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid payload to mute conversation",
+		})
+	}
+
+	authUser := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(authUser.ID)
+
+	err = s.ConversationsRepo.UpdateMemberMuted(oid, userID, dto.Muted)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.SendStatus(http.StatusOK)
+}
+
+type PinnedMessageDTO struct {
+	chatdb.PinnedMessage `json:",inline"`
+	Message              *chatdb.Message `json:"message,omitempty"`
+}
+
+// GetPinsOfConversation returns pinned messages of the conversation, the latest pinned first
+func (s ConversationsService) GetPinsOfConversation(ctx *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid id",
+		})
+	}
+
+	conversation, err := s.ConversationsRepo.GetConversationByID(oid)
+	if err != nil {
+		log.Println("can not get conversation:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get conversation",
+		})
+	}
+
+	authUser := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(authUser.ID)
+	if !slices.ContainsFunc(conversation.Members, func(m chatdb.Member) bool { return m.UserID == userID }) {
+		return ctx.Status(http.StatusForbidden).JSON(&fiber.Map{
+			"error": "user is not a member of conversation",
+		})
+	}
+
+	messageIDs := make([]primitive.ObjectID, len(conversation.Pins))
+	for i, pin := range conversation.Pins {
+		messageIDs[i] = pin.MessageID
+	}
+	messages, err := s.MessagesRepo.GetMessagesByIDs(messageIDs)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get pinned messages",
+		})
+	}
+	messageMap := make(map[primitive.ObjectID]*chatdb.Message, len(messages))
+	for i := range messages {
+		messageMap[messages[i].ID] = &messages[i]
+	}
+
+	pins := make([]PinnedMessageDTO, 0, len(conversation.Pins))
+	for i := len(conversation.Pins) - 1; i >= 0; i-- {
+		pin := conversation.Pins[i]
+		pins = append(pins, PinnedMessageDTO{PinnedMessage: pin, Message: messageMap[pin.MessageID]})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(pins)
+}
@@ -1,42 +1,370 @@
 package restapi
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
 	"blinders/packages/auth"
 	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
 	"blinders/packages/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MaxFeedbackCommentLength  = 2000
+	MaxFeedbackMetadataLength = 100
+	MaxFeedbackNoteLength     = 2000
+	MaxFeedbackScreenshots    = 3
+	MaxScreenshotSize         = 1 << 20 // 1MB, all screenshots must fit in MaxRequestBodySize
+	MaxScreenshotDimension    = 4096
+	ScreenshotsFormField      = "screenshots"
+	MaxFeedbackPageSize       = 100
 )
 
 type FeedbacksService struct {
-	Repo *usersdb.FeedbackRepo
+	Repo      *usersdb.FeedbackRepo
+	UsersRepo *usersdb.UsersRepo
+	Storage   storage.Storage
 }
 
-func NewFeedbacksService(repo *usersdb.FeedbackRepo) *FeedbacksService {
-	return &FeedbacksService{Repo: repo}
+func NewFeedbacksService(
+	repo *usersdb.FeedbackRepo,
+	usersRepo *usersdb.UsersRepo,
+	storage storage.Storage,
+) *FeedbacksService {
+	return &FeedbacksService{Repo: repo, UsersRepo: usersRepo, Storage: storage}
 }
 
+// CreateFeedbackDTO is submitted as JSON, or as multipart form to attach screenshots
 type CreateFeedbackDTO struct {
-	Comment string `json:"comment"`
+	Comment    string                   `json:"comment"    form:"comment"`
+	Category   usersdb.FeedbackCategory `json:"category"   form:"category"`
+	AppVersion string                   `json:"appVersion" form:"appVersion"`
+	Device     string                   `json:"device"     form:"device"`
+}
+
+func (dto *CreateFeedbackDTO) Validate() error {
+	dto.Comment = strings.TrimSpace(dto.Comment)
+	if dto.Comment == "" || utf8.RuneCountInString(dto.Comment) > MaxFeedbackCommentLength {
+		return fmt.Errorf("comment must have from 1 to %d characters", MaxFeedbackCommentLength)
+	}
+	if dto.Category != "" && !slices.Contains(usersdb.FeedbackCategories, dto.Category) {
+		return fmt.Errorf("category must be one of %v", usersdb.FeedbackCategories)
+	}
+	dto.AppVersion, dto.Device = strings.TrimSpace(dto.AppVersion), strings.TrimSpace(dto.Device)
+	if utf8.RuneCountInString(dto.AppVersion) > MaxFeedbackMetadataLength ||
+		utf8.RuneCountInString(dto.Device) > MaxFeedbackMetadataLength {
+		return fmt.Errorf("appVersion and device must have at most %d characters", MaxFeedbackMetadataLength)
+	}
+
+	return nil
 }
 
+// CreateFeedback submits feedback of the user. Up to 3 jpeg or png screenshots (1MB, 4096x4096 pixels)
+// could be attached in the "screenshots" files of a multipart form. Screenshots are stored in the private
+// storage under the feedback ID, they are only downloaded by moderators with DownloadScreenshot.
 func (s FeedbacksService) CreateFeedback(ctx *fiber.Ctx) error {
 	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
 	if userAuth == nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "required user auth"})
 	}
 	userID, _ := primitive.ObjectIDFromHex(userAuth.ID)
-	feedback, err := utils.ParseJSON[usersdb.Feedback](ctx.Body())
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(fiber.Map{"error": "cannot unmarshal feedback from request body"})
+
+	dto := &CreateFeedbackDTO{}
+	var screenshots []*uploadedImage
+	if strings.HasPrefix(ctx.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid multipart form"})
+		}
+		if err := ctx.BodyParser(dto); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid multipart form"})
+		}
+
+		headers := form.File[ScreenshotsFormField]
+		if len(headers) > MaxFeedbackScreenshots {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("at most %d screenshots could be attached", MaxFeedbackScreenshots),
+			})
+		}
+		if len(headers) != 0 && s.Storage == nil {
+			return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "screenshot upload is not available",
+			})
+		}
+		for _, header := range headers {
+			img, err := readUploadedImage(header, "screenshot", MaxScreenshotSize, MaxScreenshotDimension)
+			if err != nil {
+				return sendUploadError(ctx, err)
+			}
+			screenshots = append(screenshots, img)
+		}
+	} else {
+		parsed, err := utils.ParseJSON[CreateFeedbackDTO](ctx.Body())
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).
+				JSON(fiber.Map{"error": "cannot unmarshal feedback from request body"})
+		}
+		dto = parsed
+	}
+	if err := dto.Validate(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	feedbackID := primitive.NewObjectID()
+	keys := make([]string, 0, len(screenshots))
+	removeScreenshots := func() {
+		for _, key := range keys {
+			_ = s.Storage.Delete(ctx.UserContext(), key)
+		}
+	}
+	for i, img := range screenshots {
+		key := screenshotKey(feedbackID, i, img.Ext)
+		_, err := s.Storage.Put(ctx.UserContext(), key, img.ContentType, bytes.NewReader(img.Content))
+		if err != nil {
+			log.Println("can not store screenshot:", err)
+			removeScreenshots()
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot store screenshot"})
+		}
+		keys = append(keys, key)
 	}
-	feedback.UserID = userID
-	_, err = s.Repo.InsertNewFeedback(*feedback)
+
+	feedback, err := s.Repo.InsertNewFeedback(usersdb.Feedback{
+		ID:          feedbackID,
+		UserID:      userID,
+		Comment:     dto.Comment,
+		Category:    dto.Category,
+		AppVersion:  dto.AppVersion,
+		Device:      dto.Device,
+		Screenshots: keys,
+	})
 	if err != nil {
+		log.Println("can not insert feedback:", err)
+		removeScreenshots()
 		return ctx.Status(fiber.StatusBadRequest).
 			JSON(fiber.Map{"error": "cannot save feedback"})
 	}
-	return ctx.SendStatus(fiber.StatusOK)
+	return ctx.Status(fiber.StatusOK).JSON(feedback.Public())
+}
+
+type FeedbackPageDTO struct {
+	Feedback []usersdb.Feedback `json:"feedback"`
+	// Next is the cursor to get the next page of feedback, empty if there is no more feedback
+	Next string `json:"next,omitempty"`
+}
+
+// ListFeedback returns feedback by the latest first for moderators, filtered by "status", "category",
+// "assigneeId" and "userId", paginated by "limit" and the "after" cursor
+func (s FeedbacksService) ListFeedback(ctx *fiber.Ctx) error {
+	limit, after, err := parsePage(ctx, 30, MaxFeedbackPageSize)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": err.Error()})
+	}
+
+	filter := usersdb.FeedbackFilter{
+		Status:   usersdb.FeedbackStatus(ctx.Query("status")),
+		Category: usersdb.FeedbackCategory(ctx.Query("category")),
+	}
+	if _, ok := usersdb.FeedbackStatusTransitions[filter.Status]; filter.Status != "" && !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid status"})
+	}
+	if filter.Category != "" && !slices.Contains(usersdb.FeedbackCategories, filter.Category) {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid category"})
+	}
+	if filter.AssigneeID, err = parseOptionalID(ctx.Query("assigneeId")); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid assigneeId"})
+	}
+	if filter.UserID, err = parseOptionalID(ctx.Query("userId")); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid userId"})
+	}
+
+	feedback, err := s.Repo.GetFeedback(filter, after, int64(limit))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": err.Error()})
+	}
+
+	page := FeedbackPageDTO{Feedback: feedback}
+	if len(feedback) == limit {
+		page.Next = feedback[len(feedback)-1].ID.Hex()
+	}
+	return ctx.Status(http.StatusOK).JSON(page)
+}
+
+func (s FeedbacksService) GetFeedbackByID(ctx *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid feedback id"})
+	}
+
+	feedback, err := s.Repo.GetFeedbackByID(id)
+	if err != nil {
+		return sendFeedbackError(ctx, err)
+	}
+	return ctx.Status(http.StatusOK).JSON(feedback)
+}
+
+// DownloadScreenshot streams the screenshot at the "index" of the feedback for moderators
+func (s FeedbacksService) DownloadScreenshot(ctx *fiber.Ctx) error {
+	if s.Storage == nil {
+		return ctx.Status(http.StatusServiceUnavailable).JSON(&fiber.Map{"error": "screenshots are not available"})
+	}
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid feedback id"})
+	}
+	index, err := ctx.ParamsInt("index")
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid screenshot index"})
+	}
+
+	feedback, err := s.Repo.GetFeedbackByID(id)
+	if err != nil {
+		return sendFeedbackError(ctx, err)
+	}
+	if index < 0 || index >= len(feedback.Screenshots) {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{"error": "screenshot not found"})
+	}
+
+	key := feedback.Screenshots[index]
+	content, err := s.Storage.Open(ctx.UserContext(), key)
+	if err != nil {
+		log.Println("can not open screenshot:", err)
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{"error": "screenshot not found"})
+	}
+
+	ctx.Set(fiber.HeaderContentType, mime.TypeByExtension(path.Ext(key)))
+	// the screenshot is closed by fasthttp after it is sent
+	return ctx.SendStream(content)
+}
+
+// screenshotKey names screenshots by the feedback, so that they do not refer to the user who submitted them
+func screenshotKey(feedbackID primitive.ObjectID, index int, ext string) string {
+	return fmt.Sprintf("feedback/%s/%d.%s", feedbackID.Hex(), index, ext)
+}
+
+// TriageFeedbackDTO updates the set fields of feedback, an empty assigneeId unassigns the feedback
+type TriageFeedbackDTO struct {
+	Status     *usersdb.FeedbackStatus   `json:"status"`
+	Category   *usersdb.FeedbackCategory `json:"category"`
+	AssigneeID *string                   `json:"assigneeId"`
+}
+
+// TriageFeedback moves feedback through the status workflow, categorizes or assigns it.
+// Feedback could only be assigned to users who could manage feedback.
+func (s FeedbacksService) TriageFeedback(ctx *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid feedback id"})
+	}
+	dto, err := utils.ParseJSON[TriageFeedbackDTO](ctx.Body())
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid payload"})
+	}
+	if dto.Status == nil && dto.Category == nil && dto.AssigneeID == nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "require at least one of status, category and assigneeId",
+		})
+	}
+
+	triage := usersdb.FeedbackTriage{Status: dto.Status, Category: dto.Category}
+	if dto.Status != nil {
+		if _, ok := usersdb.FeedbackStatusTransitions[*dto.Status]; !ok {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid status"})
+		}
+	}
+	if dto.Category != nil && !slices.Contains(usersdb.FeedbackCategories, *dto.Category) {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid category"})
+	}
+	if dto.AssigneeID != nil && *dto.AssigneeID == "" {
+		triage.UnsetAssignee = true
+	} else if dto.AssigneeID != nil {
+		assigneeID, err := primitive.ObjectIDFromHex(*dto.AssigneeID)
+		if err != nil {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid assigneeId"})
+		}
+		assignee, err := s.UsersRepo.GetUserByID(assigneeID)
+		if err != nil {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "assignee not found"})
+		}
+		assigneeAuth := &auth.UserAuth{}
+		assigneeAuth.SetUser(assignee)
+		if !assigneeAuth.HasPermission(auth.PermissionManageFeedback) {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+				"error": "assignee can not manage feedback",
+			})
+		}
+		triage.AssigneeID = &assigneeID
+	}
+
+	feedback, err := s.Repo.UpdateFeedbackTriage(id, triage)
+	if err != nil {
+		return sendFeedbackError(ctx, err)
+	}
+	return ctx.Status(http.StatusOK).JSON(feedback)
+}
+
+type AddFeedbackNoteDTO struct {
+	Content string `json:"content"`
+}
+
+// AddFeedbackNote appends an internal note of the moderator to the feedback
+func (s FeedbacksService) AddFeedbackNote(ctx *fiber.Ctx) error {
+	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	authorID, _ := primitive.ObjectIDFromHex(userAuth.ID)
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid feedback id"})
+	}
+	dto, err := utils.ParseJSON[AddFeedbackNoteDTO](ctx.Body())
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "invalid payload"})
+	}
+	content := strings.TrimSpace(dto.Content)
+	if content == "" || utf8.RuneCountInString(content) > MaxFeedbackNoteLength {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": fmt.Sprintf("content must have from 1 to %d characters", MaxFeedbackNoteLength),
+		})
+	}
+
+	feedback, err := s.Repo.AddFeedbackNote(id, usersdb.FeedbackNote{AuthorID: authorID, Content: content})
+	if err != nil {
+		return sendFeedbackError(ctx, err)
+	}
+	return ctx.Status(http.StatusOK).JSON(feedback)
+}
+
+// parseOptionalID parses the hex ID, nil is returned for an empty string
+func parseOptionalID(raw string) (*primitive.ObjectID, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func sendFeedbackError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{"error": "feedback not found"})
+	case errors.Is(err, usersdb.ErrInvalidFeedbackTransition):
+		return ctx.Status(http.StatusConflict).JSON(&fiber.Map{"error": err.Error()})
+	default:
+		log.Println("can not update feedback:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{"error": "something went wrong"})
+	}
 }
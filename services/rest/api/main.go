@@ -5,20 +5,28 @@ import (
 	"blinders/packages/db/chatdb"
 	"blinders/packages/db/matchingdb"
 	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
 	"blinders/packages/transport"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// MaxRequestBodySize is the body limit of the rest app, the uploads must fit in it. Lambda limits payloads
+// to 6MB, and API Gateway encodes binary bodies in base64 which is a third larger than the body.
+const MaxRequestBodySize = 4 << 20 // 4MB
+
 type Manager struct {
-	App           *fiber.App
-	Auth          auth.Manager
-	UsersRepo     *usersdb.UsersRepo
-	Users         *UsersService
-	Conversations *ConversationsService
-	Messages      *MessagesService
-	Onboardings   *OnboardingService
-	Feedbacks     *FeedbacksService
+	App               *fiber.App
+	Auth              auth.Manager
+	UsersRepo         *usersdb.UsersRepo
+	Users             *UsersService
+	Conversations     *ConversationsService
+	Messages          *MessagesService
+	ScheduledMessages *ScheduledMessagesService
+	Onboardings       *OnboardingService
+	Feedbacks         *FeedbacksService
+	Accounts          *AccountsService
+	Tickets           *TicketsService
 }
 
 func NewManager(
@@ -27,8 +35,11 @@ func NewManager(
 	usersDB *usersdb.UsersDB,
 	chatDB *chatdb.ChatDB,
 	matchingRepo *matchingdb.MatchingRepo,
+	blobStorage storage.Storage,
+	privateStorage storage.Storage,
 	transporter transport.Transport,
 	consumerMap transport.ConsumerMap,
+	tickets *auth.TicketStore,
 ) *Manager {
 	return &Manager{
 		App:       app,
@@ -37,6 +48,9 @@ func NewManager(
 		Users: NewUsersService(
 			usersDB.UsersRepo,
 			usersDB.FriendRequestsRepo,
+			chatDB.ConversationsRepo,
+			matchingRepo,
+			blobStorage,
 			transporter,
 			consumerMap,
 		),
@@ -45,19 +59,40 @@ func NewManager(
 			chatDB.MessagesRepo,
 			usersDB.UsersRepo,
 		),
-		Messages: NewMessagesService(chatDB.MessagesRepo),
+		Messages: NewMessagesService(
+			chatDB.MessagesRepo,
+			chatDB.ConversationsRepo,
+		),
+		ScheduledMessages: NewScheduledMessagesService(
+			chatDB.ScheduledMessagesRepo,
+			chatDB.ConversationsRepo,
+			chatDB.MessagesRepo,
+		),
 		Onboardings: NewOnboardingService(
 			usersDB.UsersRepo,
 			matchingRepo,
 			transporter,
 			consumerMap,
 		),
-		Feedbacks: NewFeedbacksService(usersDB.FeedbackRepo),
+		Feedbacks: NewFeedbacksService(
+			usersDB.FeedbackRepo,
+			usersDB.UsersRepo,
+			privateStorage,
+		),
+		Accounts: NewAccountsService(
+			usersDB.UsersRepo,
+			usersDB.AccountJobsRepo,
+			privateStorage,
+			transporter,
+			consumerMap,
+		),
+		Tickets: NewTicketsService(tickets),
 	}
 }
 
 func (m Manager) InitRoute() error {
 	rootRoute := m.App.Group("/")
+	authUsers := auth.NewCachedUsers(m.UsersRepo, auth.NewUserCacheFromEnv())
 	rootRoute.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("hello from Peakee Rest API")
 	})
@@ -65,15 +100,21 @@ func (m Manager) InitRoute() error {
 	authorizedWithoutUser := rootRoute.Group(
 		"/users/self",
 
-		auth.FiberAuthMiddleware(m.Auth, m.UsersRepo,
+		auth.FiberAuthMiddleware(m.Auth, authUsers,
 			auth.MiddlewareOptions{
 				CheckUser: false,
 			}),
 	)
 	authorizedWithoutUser.Get("/", m.Users.GetSelfFromAuth)
 	authorizedWithoutUser.Post("/", m.Users.CreateNewUserBySelf)
+	authorizedWithoutUser.Patch("/", m.Users.UpdateSelf)
+	authorizedWithoutUser.Put("/avatar", m.Users.UploadAvatar)
+	authorizedWithoutUser.Delete("/", m.Accounts.DeleteSelf)
+	authorizedWithoutUser.Post("/exports", m.Accounts.ExportSelf)
+	authorizedWithoutUser.Get("/account-jobs/:id", m.Accounts.GetAccountJob)
+	authorizedWithoutUser.Get("/account-jobs/:id/archive", m.Accounts.DownloadExport)
 
-	authorized := rootRoute.Group("/", auth.FiberAuthMiddleware(m.Auth, m.UsersRepo))
+	authorized := rootRoute.Group("/", auth.FiberAuthMiddleware(m.Auth, authUsers))
 
 	users := authorized.Group("/users")
 	users.Get("/", m.Users.GetUsers)
@@ -85,26 +126,71 @@ func (m Manager) InitRoute() error {
 	users.Get("/:id/friend-requests",
 		ValidateUserIDParam(),
 		m.Users.GetPendingFriendRequests)
+	users.Get("/:id/friend-requests/sent",
+		ValidateUserIDParam(),
+		m.Users.GetSentFriendRequests)
 	users.Post("/:id/friend-requests",
 		ValidateUserIDParam(),
 		m.Users.CreateAddFriendRequest)
+	users.Delete("/:id/friend-requests/:requestId",
+		ValidateUserIDParam(),
+		m.Users.CancelFriendRequest)
 	users.Put(
 		"/:id/friend-requests/:requestId",
 		ValidateUserIDParam(),
 		m.Users.RespondFriendRequest)
+	users.Get("/:id/friends",
+		ValidateUserIDParam(),
+		m.Users.GetFriends)
+	users.Delete("/:id/friends/:friendId",
+		ValidateUserIDParam(),
+		m.Users.RemoveFriend)
+	users.Put("/:id/blocks/:blockedId",
+		ValidateUserIDParam(),
+		m.Users.BlockUser)
+	users.Delete("/:id/blocks/:blockedId",
+		ValidateUserIDParam(),
+		m.Users.UnblockUser)
 
 	// TODO: need to check if this user is in the conversation
 	conversations := authorized.Group("/conversations")
 	conversations.Get("/:id", m.Conversations.GetConversationByID)
 	conversations.Get("/:id/messages", m.Conversations.GetMessagesOfConversation)
+	conversations.Get("/:id/pins", m.Conversations.GetPinsOfConversation)
+	conversations.Put("/:id/mute", m.Conversations.MuteConversation)
 	conversations.Get("/", m.Conversations.GetConversationsOfUser)
 	conversations.Post("/", m.Conversations.CreateNewIndividualConversation)
+	conversations.Post("/partner-bot", m.Conversations.GetOrCreatePartnerBotConversation)
+	conversations.Post("/:id/scheduled-messages", m.ScheduledMessages.ScheduleMessage)
+
+	scheduledMessages := authorized.Group("/scheduled-messages")
+	scheduledMessages.Get("/", m.ScheduledMessages.GetScheduledMessages)
+	scheduledMessages.Delete("/:id", m.ScheduledMessages.CancelScheduledMessage)
 
 	authorized.Get("/messages/:id", m.Messages.GetMessageByID)
+	authorized.Get("/messages/:id/thread", m.Messages.GetThreadOfMessage)
 
 	authorized.Post("/onboarding", m.Onboardings.PostOnboardingForm())
 
 	authorized.Post("/feedback", m.Feedbacks.CreateFeedback)
 
+	authorized.Post("/websocket-tickets", m.Tickets.CreateWebsocketTicket)
+
+	admin := authorized.Group("/admin")
+	adminFeedback := admin.Group("/feedback")
+	adminFeedback.Get("/", auth.RequirePermission(auth.PermissionReadFeedback), m.Feedbacks.ListFeedback)
+	adminFeedback.Get("/:id", auth.RequirePermission(auth.PermissionReadFeedback), m.Feedbacks.GetFeedbackByID)
+	adminFeedback.Get(
+		"/:id/screenshots/:index",
+		auth.RequirePermission(auth.PermissionReadFeedback),
+		m.Feedbacks.DownloadScreenshot,
+	)
+	adminFeedback.Patch("/:id", auth.RequirePermission(auth.PermissionManageFeedback), m.Feedbacks.TriageFeedback)
+	adminFeedback.Post(
+		"/:id/notes",
+		auth.RequirePermission(auth.PermissionManageFeedback),
+		m.Feedbacks.AddFeedbackNote,
+	)
+
 	return nil
 }
@@ -3,7 +3,9 @@ package restapi
 import (
 	"log"
 	"net/http"
+	"slices"
 
+	"blinders/packages/auth"
 	"blinders/packages/db/chatdb"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,12 +13,17 @@ import (
 )
 
 type MessagesService struct {
-	Repo *chatdb.MessagesRepo
+	Repo              *chatdb.MessagesRepo
+	ConversationsRepo *chatdb.ConversationsRepo
 }
 
-func NewMessagesService(repo *chatdb.MessagesRepo) *MessagesService {
+func NewMessagesService(
+	repo *chatdb.MessagesRepo,
+	conversationsRepo *chatdb.ConversationsRepo,
+) *MessagesService {
 	return &MessagesService{
-		Repo: repo,
+		Repo:              repo,
+		ConversationsRepo: conversationsRepo,
 	}
 }
 
@@ -40,3 +47,79 @@ func (s MessagesService) GetMessageByID(ctx *fiber.Ctx) error {
 
 	return ctx.Status(http.StatusOK).JSON(message)
 }
+
+const MaxThreadPageSize = 100
+
+type ThreadDTO struct {
+	Root    chatdb.Message   `json:"root"`
+	Replies []chatdb.Message `json:"replies"`
+	// Next is the cursor to get the next page of replies, empty if there is no more reply
+	Next string `json:"next,omitempty"`
+}
+
+// GetThreadOfMessage returns the thread which the message belongs to, replies are paginated in sending order
+// by "limit" and the "after" cursor. Requesting a reply returns the whole thread of its root message.
+func (s MessagesService) GetThreadOfMessage(ctx *fiber.Ctx) error {
+	oid, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid id",
+		})
+	}
+
+	limit, after, err := parsePage(ctx, 30, MaxThreadPageSize)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	root, err := s.Repo.GetMessageByID(oid)
+	if err != nil {
+		log.Println("can not get message:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get message",
+		})
+	}
+	if root.ThreadID != nil {
+		root, err = s.Repo.GetMessageByID(*root.ThreadID)
+		if err != nil {
+			log.Println("can not get thread root:", err)
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+				"error": "can not get thread",
+			})
+		}
+	}
+
+	conversation, err := s.ConversationsRepo.GetConversationByID(root.ConversationID)
+	if err != nil {
+		log.Println("can not get conversation:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get conversation",
+		})
+	}
+
+	authUser := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(authUser.ID)
+	if !slices.ContainsFunc(conversation.Members, func(m chatdb.Member) bool { return m.UserID == userID }) {
+		return ctx.Status(http.StatusForbidden).JSON(&fiber.Map{
+			"error": "user is not a member of conversation",
+		})
+	}
+
+	// query one more reply to know if there is a next page
+	replies, err := s.Repo.GetRepliesOfThread(root.ID, after, int64(limit+1))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get replies",
+		})
+	}
+
+	thread := ThreadDTO{Root: root, Replies: replies}
+	if len(replies) > limit {
+		thread.Replies = replies[:limit]
+		thread.Next = replies[limit-1].ID.Hex()
+	}
+
+	return ctx.Status(http.StatusOK).JSON(thread)
+}
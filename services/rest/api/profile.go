@@ -0,0 +1,198 @@
+package restapi
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"blinders/packages/auth"
+	"blinders/packages/db/usersdb"
+	"blinders/packages/storage"
+	"blinders/packages/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MaxNameLength      = 50
+	MaxBioLength       = 300
+	MaxAvatarSize      = 2 << 20 // 2MB
+	MaxAvatarDimension = 4096
+	AvatarFormField    = "avatar"
+)
+
+type UpdateSelfDTO struct {
+	Name     *string `json:"name"`
+	ImageURL *string `json:"imageUrl"`
+	Bio      *string `json:"bio"`
+}
+
+// Validate rejects imageUrl of the blob storage, avatars of the storage are only set by UploadAvatar
+// so that users could not take avatars of others, which are deleted when the avatar is replaced.
+func (dto *UpdateSelfDTO) Validate(blobStorage storage.Storage) error {
+	if dto.Name == nil && dto.ImageURL == nil && dto.Bio == nil {
+		return fmt.Errorf("require at least one of name, imageUrl and bio")
+	}
+	if dto.Name != nil {
+		name := strings.TrimSpace(*dto.Name)
+		if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+			return fmt.Errorf("name must have from 1 to %d characters", MaxNameLength)
+		}
+		dto.Name = &name
+	}
+	if dto.Bio != nil {
+		bio := strings.TrimSpace(*dto.Bio)
+		if utf8.RuneCountInString(bio) > MaxBioLength {
+			return fmt.Errorf("bio must have at most %d characters", MaxBioLength)
+		}
+		dto.Bio = &bio
+	}
+	if dto.ImageURL != nil && *dto.ImageURL != "" {
+		u, err := url.Parse(*dto.ImageURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("imageUrl must be a http(s) url")
+		}
+		if blobStorage != nil {
+			if _, ok := blobStorage.KeyOf(*dto.ImageURL); ok {
+				return fmt.Errorf("imageUrl of the storage must be uploaded as avatar")
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateSelf updates profile of the user, the changed name is propagated to the matching information
+func (s UsersService) UpdateSelf(ctx *fiber.Ctx) error {
+	user, err := s.getSelf(ctx)
+	if err != nil {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	dto, err := utils.ParseJSON[UpdateSelfDTO](ctx.Body())
+	if err != nil {
+		log.Println("invalid payload:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid payload",
+		})
+	}
+	if err := dto.Validate(s.Storage); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updated, err := s.updateProfile(*user, usersdb.UserProfileUpdate{
+		Name:     dto.Name,
+		ImageURL: dto.ImageURL,
+		Bio:      dto.Bio,
+	})
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(updated.Self())
+}
+
+// UploadAvatar stores the avatar in the blob storage and sets it as the image of the user,
+// only jpeg and png images up to 2MB and 4096x4096 pixels are accepted
+func (s UsersService) UploadAvatar(ctx *fiber.Ctx) error {
+	if s.Storage == nil {
+		return ctx.Status(http.StatusServiceUnavailable).JSON(&fiber.Map{
+			"error": "avatar upload is not available",
+		})
+	}
+
+	user, err := s.getSelf(ctx)
+	if err != nil {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	header, err := ctx.FormFile(AvatarFormField)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": fmt.Sprintf("require %s file", AvatarFormField),
+		})
+	}
+	img, err := readUploadedImage(header, "avatar", MaxAvatarSize, MaxAvatarDimension)
+	if err != nil {
+		return sendUploadError(ctx, err)
+	}
+
+	key := avatarKeyPrefix(user.ID) + primitive.NewObjectID().Hex() + "." + img.Ext
+	imageURL, err := s.Storage.Put(ctx.UserContext(), key, img.ContentType, bytes.NewReader(img.Content))
+	if err != nil {
+		log.Println("can not store avatar:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not store avatar",
+		})
+	}
+
+	updated, err := s.updateProfile(*user, usersdb.UserProfileUpdate{ImageURL: &imageURL})
+	if err != nil {
+		_ = s.Storage.Delete(ctx.UserContext(), key)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// remove the previous avatar if it was uploaded to the storage by the user
+	if oldKey, ok := s.Storage.KeyOf(user.ImageURL); ok && strings.HasPrefix(oldKey, avatarKeyPrefix(user.ID)) {
+		if err := s.Storage.Delete(ctx.UserContext(), oldKey); err != nil {
+			log.Println("can not delete previous avatar:", err)
+		}
+	}
+
+	return ctx.Status(http.StatusOK).JSON(updated.Self())
+}
+
+func avatarKeyPrefix(userID primitive.ObjectID) string {
+	return fmt.Sprintf("avatars/%s/", userID.Hex())
+}
+
+func (s UsersService) updateProfile(user usersdb.User, update usersdb.UserProfileUpdate) (*usersdb.User, error) {
+	updated, err := s.UsersRepo.UpdateUserProfile(user.ID, update)
+	if err != nil {
+		log.Println("can not update user:", err)
+		return nil, fmt.Errorf("can not update user")
+	}
+
+	if update.Name != nil && *update.Name != user.Name && s.MatchingRepo != nil {
+		err := s.MatchingRepo.UpdateNameByUserID(user.ID, *update.Name)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Println("can not update name of matching information:", err)
+		}
+	}
+
+	return &updated, nil
+}
+
+// getSelf returns the user of the request, the routes of self do not check the user in the auth middleware
+func (s UsersService) getSelf(ctx *fiber.Ctx) (*usersdb.User, error) {
+	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	if userAuth == nil {
+		return nil, fmt.Errorf("required user auth")
+	}
+
+	user, err := s.UsersRepo.GetUserByFirebaseUID(userAuth.AuthID)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Println("can not get user:", err)
+		}
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,50 @@
+package restapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"blinders/packages/storage"
+	restapi "blinders/services/rest/api"
+
+	"github.com/test-go/testify/assert"
+)
+
+func TestValidateUpdateSelf(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	blobStorage, err := storage.NewLocalStorage(t.TempDir(), "http://localhost:8083/storage")
+	assert.Nil(t, err)
+
+	assert.NotNil(t, (&restapi.UpdateSelfDTO{}).Validate(blobStorage))
+	assert.NotNil(t, (&restapi.UpdateSelfDTO{Name: ptr("  ")}).Validate(blobStorage))
+	assert.NotNil(t, (&restapi.UpdateSelfDTO{Name: ptr(strings.Repeat("a", restapi.MaxNameLength+1))}).Validate(blobStorage))
+	assert.NotNil(t, (&restapi.UpdateSelfDTO{Bio: ptr(strings.Repeat("a", restapi.MaxBioLength+1))}).Validate(blobStorage))
+	assert.NotNil(t, (&restapi.UpdateSelfDTO{ImageURL: ptr("javascript:alert(1)")}).Validate(blobStorage))
+	// avatars of other users could not be taken to be deleted on the next upload
+	assert.NotNil(t, (&restapi.UpdateSelfDTO{
+		ImageURL: ptr("http://localhost:8083/storage/avatars/other/avatar.png"),
+	}).Validate(blobStorage))
+
+	dto := restapi.UpdateSelfDTO{Name: ptr(" Peakee "), Bio: ptr(""), ImageURL: ptr("https://example.com/a.png")}
+	assert.Nil(t, dto.Validate(blobStorage))
+	assert.Equal(t, "Peakee", *dto.Name)
+	assert.Nil(t, (&restapi.UpdateSelfDTO{ImageURL: ptr("")}).Validate(blobStorage))
+	assert.Nil(t, (&restapi.UpdateSelfDTO{ImageURL: ptr("https://example.com/a.png")}).Validate(nil))
+}
+
+func TestValidateCreateFeedback(t *testing.T) {
+	assert.NotNil(t, (&restapi.CreateFeedbackDTO{Comment: "  "}).Validate())
+	assert.NotNil(t, (&restapi.CreateFeedbackDTO{
+		Comment: strings.Repeat("a", restapi.MaxFeedbackCommentLength+1),
+	}).Validate())
+	assert.NotNil(t, (&restapi.CreateFeedbackDTO{Comment: "comment", Category: "unknown"}).Validate())
+	assert.NotNil(t, (&restapi.CreateFeedbackDTO{
+		Comment: "comment",
+		Device:  strings.Repeat("a", restapi.MaxFeedbackMetadataLength+1),
+	}).Validate())
+
+	dto := restapi.CreateFeedbackDTO{Comment: " comment ", Category: "bug", AppVersion: " 1.2.0 "}
+	assert.Nil(t, dto.Validate())
+	assert.Equal(t, "comment", dto.Comment)
+	assert.Equal(t, "1.2.0", dto.AppVersion)
+}
@@ -0,0 +1,175 @@
+package restapi
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"blinders/packages/auth"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxScheduleAhead limits how far in the future a message could be scheduled
+const MaxScheduleAhead = time.Hour * 24 * 30
+
+type ScheduledMessagesService struct {
+	Repo              *chatdb.ScheduledMessagesRepo
+	ConversationsRepo *chatdb.ConversationsRepo
+	MessagesRepo      *chatdb.MessagesRepo
+}
+
+func NewScheduledMessagesService(
+	repo *chatdb.ScheduledMessagesRepo,
+	convRepo *chatdb.ConversationsRepo,
+	messagesRepo *chatdb.MessagesRepo,
+) *ScheduledMessagesService {
+	return &ScheduledMessagesService{
+		Repo:              repo,
+		ConversationsRepo: convRepo,
+		MessagesRepo:      messagesRepo,
+	}
+}
+
+type ScheduleMessageDTO struct {
+	Content     string    `json:"content"`
+	ReplyTo     string    `json:"replyTo"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+}
+
+func (s ScheduledMessagesService) ScheduleMessage(ctx *fiber.Ctx) error {
+	conversationID, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid id",
+		})
+	}
+
+	dto, err := utils.ParseJSON[ScheduleMessageDTO](ctx.Body())
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid payload to schedule message",
+		})
+	}
+	if strings.TrimSpace(dto.Content) == "" {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "content is required",
+		})
+	}
+	// slash commands are executed for the sender when they are sent, they could not be scheduled
+	if _, ok := chatdb.ParseCommand(dto.Content); ok {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "commands could not be scheduled",
+		})
+	}
+	now := time.Now()
+	if !dto.ScheduledAt.After(now) || dto.ScheduledAt.After(now.Add(MaxScheduleAhead)) {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "scheduledAt must be in the future, within 30 days",
+		})
+	}
+
+	authUser := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(authUser.ID)
+
+	conversation, err := s.ConversationsRepo.GetConversationByID(conversationID)
+	if err != nil ||
+		!slices.ContainsFunc(conversation.Members, func(m chatdb.Member) bool { return m.UserID == userID }) {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "user is not a member of conversation",
+		})
+	}
+	if conversation.ReadOnly {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "conversation is read only",
+		})
+	}
+
+	scheduled := chatdb.ScheduledMessage{
+		SenderID:       userID,
+		ConversationID: conversationID,
+		Content:        dto.Content,
+		ScheduledAt:    primitive.NewDateTimeFromTime(dto.ScheduledAt),
+	}
+	if dto.ReplyTo != "" {
+		replyTo, err := primitive.ObjectIDFromHex(dto.ReplyTo)
+		if err != nil {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+				"error": "invalid replyTo",
+			})
+		}
+		repliedMessage, err := s.MessagesRepo.GetMessageByID(replyTo)
+		if err != nil || repliedMessage.ConversationID != conversationID {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+				"error": "cannot reply to message " + dto.ReplyTo,
+			})
+		}
+		scheduled.ReplyTo = &replyTo
+	}
+
+	inserted, err := s.Repo.InsertNewRawScheduledMessage(scheduled)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(inserted)
+}
+
+// GetScheduledMessages returns scheduled messages of the current user,
+// filtered by "status" query, pending messages by default
+func (s ScheduledMessagesService) GetScheduledMessages(ctx *fiber.Ctx) error {
+	authUser := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(authUser.ID)
+
+	var statuses []chatdb.ScheduledMessageStatus
+	switch status := ctx.Query("status", string(chatdb.PendingScheduledMessage)); status {
+	case "all":
+	case string(chatdb.PendingScheduledMessage),
+		string(chatdb.SentScheduledMessage),
+		string(chatdb.CancelledScheduledMessage),
+		string(chatdb.FailedScheduledMessage):
+		statuses = append(statuses, chatdb.ScheduledMessageStatus(status))
+	default:
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid status, must be 'all', 'pending', 'sent', 'cancelled' or 'failed'",
+		})
+	}
+
+	messages, err := s.Repo.GetScheduledMessagesOfSender(userID, statuses...)
+	if err != nil {
+		log.Println("can not get scheduled messages:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get scheduled messages",
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(messages)
+}
+
+func (s ScheduledMessagesService) CancelScheduledMessage(ctx *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid id",
+		})
+	}
+
+	authUser := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(authUser.ID)
+
+	cancelled, err := s.Repo.CancelScheduledMessage(id, userID)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(cancelled)
+}
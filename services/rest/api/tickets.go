@@ -0,0 +1,49 @@
+package restapi
+
+import (
+	"log"
+	"net/http"
+
+	"blinders/packages/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TicketsService struct {
+	// Tickets is the ticket store of the deployment, tickets are not available if it is nil
+	Tickets *auth.TicketStore
+}
+
+func NewTicketsService(tickets *auth.TicketStore) *TicketsService {
+	return &TicketsService{Tickets: tickets}
+}
+
+type WebsocketTicketResponse struct {
+	Ticket string `json:"ticket"`
+	// ExpiresIn is the number of seconds the ticket could be used in
+	ExpiresIn int `json:"expiresIn"`
+}
+
+// CreateWebsocketTicket exchanges the bearer token for a single-use ticket of websocket connections,
+// which is sent by the "ticket" query or the "ticket.<ticket>" subprotocol instead of the token
+func (s TicketsService) CreateWebsocketTicket(ctx *fiber.Ctx) error {
+	if s.Tickets == nil {
+		return ctx.Status(http.StatusServiceUnavailable).JSON(&fiber.Map{"error": "ticket is not available"})
+	}
+
+	userAuth, ok := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	if !ok || userAuth == nil {
+		return ctx.Status(http.StatusUnauthorized).JSON(&fiber.Map{"error": "required user auth"})
+	}
+
+	ticket, err := s.Tickets.Issue(ctx.UserContext(), *userAuth)
+	if err != nil {
+		log.Println("can not issue ticket:", err)
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{"error": "can not issue ticket"})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(WebsocketTicketResponse{
+		Ticket:    ticket,
+		ExpiresIn: int(s.Tickets.TTL.Seconds()),
+	})
+}
@@ -0,0 +1,83 @@
+package restapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// image content types which are accepted for uploads, mapped to file extensions
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+}
+
+// uploadedImage is a validated jpeg or png image of a multipart form
+type uploadedImage struct {
+	Content     []byte
+	ContentType string
+	Ext         string
+}
+
+// readUploadedImage reads the image file and validates its size, format and dimensions, the name is used
+// in error messages. Errors are *fiber.Error with the status to respond.
+func readUploadedImage(
+	header *multipart.FileHeader,
+	name string,
+	maxSize int64,
+	maxDimension int,
+) (*uploadedImage, error) {
+	if header.Size > maxSize {
+		return nil, fiber.NewError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("%s must be at most %d bytes", name, maxSize))
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("can not open %s: %v\n", name, err)
+		return nil, fiber.NewError(http.StatusBadRequest, fmt.Sprintf("can not read %s", name))
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil || int64(len(content)) > maxSize {
+		return nil, fiber.NewError(http.StatusBadRequest, fmt.Sprintf("can not read %s", name))
+	}
+
+	// the declared content type is not trusted, the type is detected from the content
+	contentType := http.DetectContentType(content)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, fiber.NewError(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("%s must be a jpeg or png image", name))
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, fmt.Sprintf("%s is not a valid image", name))
+	}
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return nil, fiber.NewError(http.StatusBadRequest,
+			fmt.Sprintf("%s must be at most %dx%d pixels", name, maxDimension, maxDimension))
+	}
+
+	return &uploadedImage{Content: content, ContentType: contentType, Ext: ext}, nil
+}
+
+func sendUploadError(ctx *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if !errors.As(err, &fiberErr) {
+		fiberErr = fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	return ctx.Status(fiberErr.Code).JSON(&fiber.Map{
+		"error": fiberErr.Message,
+	})
+}
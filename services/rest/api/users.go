@@ -6,9 +6,18 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"blinders/packages/auth"
+	"blinders/packages/db/chatdb"
+	"blinders/packages/db/matchingdb"
 	"blinders/packages/db/usersdb"
+	dbutils "blinders/packages/db/utils"
+	"blinders/packages/storage"
 	"blinders/packages/transport"
 	"blinders/packages/utils"
 
@@ -21,19 +30,29 @@ import (
 type UsersService struct {
 	UsersRepo          *usersdb.UsersRepo
 	FriendRequestsRepo *usersdb.FriendRequestsRepo
-	Transporter        transport.Transport
-	ConsumerMap        transport.ConsumerMap
+	ConversationsRepo  *chatdb.ConversationsRepo
+	MatchingRepo       *matchingdb.MatchingRepo
+	// Storage stores avatars of users, avatar upload is not available if it is nil
+	Storage     storage.Storage
+	Transporter transport.Transport
+	ConsumerMap transport.ConsumerMap
 }
 
 func NewUsersService(
 	repo *usersdb.UsersRepo,
 	frRepo *usersdb.FriendRequestsRepo,
+	convRepo *chatdb.ConversationsRepo,
+	matchingRepo *matchingdb.MatchingRepo,
+	blobStorage storage.Storage,
 	transporter transport.Transport,
 	consumerMap transport.ConsumerMap,
 ) *UsersService {
 	return &UsersService{
 		UsersRepo:          repo,
 		FriendRequestsRepo: frRepo,
+		ConversationsRepo:  convRepo,
+		MatchingRepo:       matchingRepo,
+		Storage:            blobStorage,
 		Transporter:        transporter,
 		ConsumerMap:        consumerMap,
 	}
@@ -52,11 +71,11 @@ func (s UsersService) GetSelfFromAuth(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	return ctx.Status(http.StatusOK).JSON(user)
+	return ctx.Status(http.StatusOK).JSON(user.Self())
 }
 
+// GetUserByID returns the self projection of the user, or the public projection on public queries
 func (s UsersService) GetUserByID(ctx *fiber.Ctx) error {
-	// TODO: need to check if this is a public query and eliminate private data
 	id := ctx.Params("id")
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -74,21 +93,182 @@ func (s UsersService) GetUserByID(ctx *fiber.Ctx) error {
 		})
 	}
 
-	return ctx.Status(http.StatusOK).JSON(user)
+	if isPublicQuery, _ := ctx.Locals(PublicQuery).(bool); isPublicQuery {
+		return ctx.Status(http.StatusOK).JSON(user.Public())
+	}
+	return ctx.Status(http.StatusOK).JSON(user.Self())
 }
 
+const (
+	MaxSearchUsersPageSize = 50
+	MaxSearchQueryLength   = 100
+)
+
+type UsersDTO struct {
+	Users []usersdb.PublicUser `json:"users"`
+	// Next is the cursor to get the next page of users, empty if there is no more user
+	Next string `json:"next,omitempty"`
+}
+
+// GetUsers finds users by exact "email", or searches users whose name or email starts with "q" case-insensitively,
+// search results are paginated by "limit" and the "after" cursor. Blocked users are excluded in both ways.
 func (s UsersService) GetUsers(ctx *fiber.Ctx) error {
+	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	userID, _ := primitive.ObjectIDFromHex(userAuth.ID)
+	user, err := s.UsersRepo.GetUserByID(userID)
+	if err != nil {
+		log.Println("can not get user:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get user",
+		})
+	}
+
 	email := ctx.Query("email", "")
 	if email != "" {
-		user, err := s.UsersRepo.GetUserByEmail(email)
+		found, err := s.UsersRepo.GetUserByEmail(email)
 		if err != nil {
 			return ctx.SendStatus(http.StatusBadRequest)
 		}
+		if slices.Contains(user.BlockedIDs, found.ID) || slices.Contains(found.BlockedIDs, user.ID) {
+			return ctx.Status(http.StatusOK).JSON([]usersdb.PublicUser{})
+		}
+
+		return ctx.Status(http.StatusOK).JSON([]usersdb.PublicUser{found.Public()})
+	}
+
+	query := strings.TrimSpace(ctx.Query("q", ""))
+	if length := utf8.RuneCountInString(query); length < usersdb.MinNameSearchQueryLength ||
+		length > MaxSearchQueryLength {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": fmt.Sprintf(
+				"email or q of %d to %d characters is required",
+				usersdb.MinNameSearchQueryLength,
+				MaxSearchQueryLength,
+			),
+		})
+	}
+	limit, after, err := parsePage(ctx, 20, MaxSearchUsersPageSize)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	users, err := s.UsersRepo.SearchUsers(user, query, after, int64(limit+1))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	dto := UsersDTO{Users: users}
+	if len(users) > limit {
+		dto.Users = users[:limit]
+		dto.Next = users[limit-1].ID.Hex()
+	}
+
+	return ctx.Status(http.StatusOK).JSON(dto)
+}
+
+// parsePage parses the "limit" and the "after" cursor of paginated queries
+func parsePage(ctx *fiber.Ctx, defaultLimit int, maxLimit int) (int, *primitive.ObjectID, error) {
+	limit, err := strconv.Atoi(ctx.Query("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit <= 0 || limit > maxLimit {
+		return 0, nil, fmt.Errorf("limit must be from 1 to %d", maxLimit)
+	}
+
+	rawAfter := ctx.Query("after")
+	if rawAfter == "" {
+		return limit, nil, nil
+	}
+	after, err := primitive.ObjectIDFromHex(rawAfter)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid after")
+	}
+
+	return limit, &after, nil
+}
+
+const MaxFriendsPageSize = 100
+
+type FriendsDTO struct {
+	Friends []usersdb.FriendProfile `json:"friends"`
+	// Next is the cursor to get the next page of friends, empty if there is no more friend
+	Next string `json:"next,omitempty"`
+}
+
+// GetFriends returns profiles of friends of the user with their languages, country and mutual friends count,
+// friends are paginated by "limit" and the "after" cursor. The page is read in one aggregation only if
+// USERS_MONGO_DATABASE and MATCHING_MONGO_DATABASE are the same database. Deployed configs use separate
+// databases, so matching information of the page is read with a second query.
+func (s UsersService) GetFriends(ctx *fiber.Ctx) error {
+	userID, _ := primitive.ObjectIDFromHex(ctx.Params("id"))
+	limit, after, err := parsePage(ctx, 30, MaxFriendsPageSize)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// matching information could be joined in the pipeline only if it is stored in the users database,
+	// otherwise it is queried separately for friends of the page
+	joinMatchInfo := s.MatchingRepo != nil &&
+		s.MatchingRepo.Database().Client() == s.UsersRepo.Database().Client() &&
+		s.MatchingRepo.Database().Name() == s.UsersRepo.Database().Name()
+	friends, err := s.UsersRepo.GetFriendProfilesOfUser(userID, after, int64(limit+1), joinMatchInfo)
+	if err == mongo.ErrNoDocuments {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get user",
+		})
+	} else if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	dto := FriendsDTO{Friends: friends}
+	if len(friends) > limit {
+		dto.Friends = friends[:limit]
+		dto.Next = friends[limit-1].ID.Hex()
+	}
+	if !joinMatchInfo && s.MatchingRepo != nil {
+		s.hydrateFriendProfiles(dto.Friends)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(dto)
+}
 
-		return ctx.Status(http.StatusOK).JSON([]usersdb.User{user})
+func (s UsersService) hydrateFriendProfiles(friends []usersdb.FriendProfile) {
+	if len(friends) == 0 {
+		return
+	}
+	ids := make([]primitive.ObjectID, len(friends))
+	for i, f := range friends {
+		ids[i] = f.ID
 	}
 
-	return nil
+	c, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	cur, err := s.MatchingRepo.Find(c, bson.M{"userId": bson.M{"$in": ids}})
+	if err != nil {
+		log.Println("can not get match info of friends:", err)
+		return
+	}
+	var infos []matchingdb.MatchInfo
+	if err := cur.All(c, &infos); err != nil {
+		log.Println("can not decode match info of friends:", err)
+		return
+	}
+
+	infoOfUser := make(map[primitive.ObjectID]matchingdb.MatchInfo, len(infos))
+	for _, info := range infos {
+		infoOfUser[info.UserID] = info
+	}
+	for i := range friends {
+		if info, ok := infoOfUser[friends[i].ID]; ok {
+			friends[i].Native, friends[i].Learnings, friends[i].Country = info.Native, info.Learnings, info.Country
+		}
+	}
 }
 
 type CreateUserDTO struct {
@@ -105,20 +285,31 @@ func (s UsersService) CreateNewUserBySelf(ctx *fiber.Ctx) error {
 			"error": "invalid payload",
 		})
 	}
-	if userDTO.Email == "" || userDTO.Name == "" {
-		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
-			"error": "invalid payload, require email and name",
+
+	userAuth, ok := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
+	if !ok || userAuth == nil {
+		return ctx.Status(http.StatusUnauthorized).JSON(&fiber.Map{
+			"error": "required user auth",
 		})
 	}
 
-	userAuth := ctx.Locals(auth.UserAuthKey).(*auth.UserAuth)
-	if userAuth == nil {
-		return fmt.Errorf("required user auth")
+	// users signed in by phone or anonymously have no email, name falls back to the display name of the token
+	email, name := strings.TrimSpace(userDTO.Email), strings.TrimSpace(userDTO.Name)
+	if email == "" {
+		email = userAuth.Email
+	}
+	if name == "" {
+		name = userAuth.Name
+	}
+	if name == "" {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid payload, require name",
+		})
 	}
 
 	user, err := s.UsersRepo.InsertNewRawUser(usersdb.User{
-		Name:        userDTO.Name,
-		Email:       userDTO.Email,
+		Name:        name,
+		Email:       email,
 		ImageURL:    userDTO.ImageURL,
 		FirebaseUID: userAuth.AuthID,
 		FriendIDs:   make([]primitive.ObjectID, 0),
@@ -130,9 +321,17 @@ func (s UsersService) CreateNewUserBySelf(ctx *fiber.Ctx) error {
 		})
 	}
 
-	return ctx.Status(http.StatusCreated).JSON(user)
+	return ctx.Status(http.StatusCreated).JSON(user.Self())
 }
 
+const (
+	// MaxPendingFriendRequests limits pending requests sent from a user
+	MaxPendingFriendRequests = 50
+	// MaxFriendRequestsToUserPerDay limits requests sent to the same user in a day,
+	// including cancelled and denied ones
+	MaxFriendRequestsToUserPerDay = 3
+)
+
 func (s UsersService) GetPendingFriendRequests(ctx *fiber.Ctx) error {
 	userID, err := primitive.ObjectIDFromHex(ctx.Params("id"))
 	if err != nil {
@@ -150,10 +349,39 @@ func (s UsersService) GetPendingFriendRequests(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if len(requests) == 0 {
-		requests = make([]usersdb.FriendRequest, 0)
+	return ctx.Status(http.StatusOK).JSON(s.friendRequestDTOs(requests...))
+}
+
+// GetSentFriendRequests returns requests sent from the user, pending ones by default,
+// other statuses could be queried by `?status=`, `?status=all` for all requests
+func (s UsersService) GetSentFriendRequests(ctx *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		log.Println("invalid user id:", err)
+		return err
+	}
+
+	status := usersdb.FriendRequestStatus(ctx.Query("status", string(usersdb.FriendStatusPending)))
+	switch status {
+	case usersdb.FriendStatusPending,
+		usersdb.FriendStatusAccepted,
+		usersdb.FriendStatusDenied,
+		usersdb.FriendStatusCancelled,
+		"all":
+	default:
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid status",
+		})
+	}
+
+	requests, err := s.FriendRequestsRepo.GetFriendRequestByFrom(userID, status)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
 	}
-	return ctx.Status(http.StatusOK).JSON(requests)
+
+	return ctx.Status(http.StatusOK).JSON(s.friendRequestDTOs(requests...))
 }
 
 type AddFriendRequest struct {
@@ -180,15 +408,77 @@ func (s UsersService) CreateAddFriendRequest(ctx *fiber.Ctx) error {
 		})
 	}
 
+	if friendID == userID {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not add yourself as friend",
+		})
+	}
+	if _, err := s.UsersRepo.GetUserByID(friendID); err != nil {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{
+			"error": "user not found",
+		})
+	}
+
 	var user usersdb.User
 	err = s.UsersRepo.FindOne(context.Background(), bson.M{
 		"_id":     userID,
 		"friends": bson.M{"$all": []primitive.ObjectID{friendID}},
 	}).Decode(&user)
-	if err != mongo.ErrNoDocuments {
+	if err == nil {
 		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
 			"error": "user already added as friend",
 		})
+	} else if err != mongo.ErrNoDocuments {
+		log.Println("can not check friendship:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "something went wrong",
+		})
+	}
+
+	// the friend has already sent a request to the user, accept it instead of creating another one
+	acceptReverse := func(reverse *usersdb.FriendRequest) error {
+		r, err := s.acceptFriendRequest(reverse.ID, userID)
+		if err != nil {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		s.pushFriendRequestEvent(friendID, r.ID, transport.AcceptFriendRequest)
+		return ctx.Status(http.StatusAccepted).JSON(s.friendRequestDTOs(*r)[0])
+	}
+	reverse, err := s.FriendRequestsRepo.GetPendingFriendRequestBetween(friendID, userID)
+	if err == nil {
+		return acceptReverse(reverse)
+	} else if err != mongo.ErrNoDocuments {
+		log.Println("can not get friend request:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "something went wrong",
+		})
+	}
+
+	pending, err := s.FriendRequestsRepo.CountPendingFriendRequestsByFrom(userID)
+	if err != nil {
+		log.Println("can not count pending friend requests:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "something went wrong",
+		})
+	}
+	if pending >= MaxPendingFriendRequests {
+		return ctx.Status(http.StatusTooManyRequests).JSON(&fiber.Map{
+			"error": fmt.Sprintf("can not have more than %d pending requests", MaxPendingFriendRequests),
+		})
+	}
+	sent, err := s.FriendRequestsRepo.CountFriendRequestsSince(userID, friendID, time.Now().Add(-time.Hour*24))
+	if err != nil {
+		log.Println("can not count friend requests:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "something went wrong",
+		})
+	}
+	if sent >= MaxFriendRequestsToUserPerDay {
+		return ctx.Status(http.StatusTooManyRequests).JSON(&fiber.Map{
+			"error": "too many requests to this user, try again later",
+		})
 	}
 
 	r, err := s.FriendRequestsRepo.InsertNewRawFriendRequest(
@@ -197,22 +487,99 @@ func (s UsersService) CreateAddFriendRequest(ctx *fiber.Ctx) error {
 			To:     friendID,
 			Status: usersdb.FriendStatusPending,
 		})
+	if err == usersdb.ErrFriendRequestExisted {
+		// pending requests are unique by the pair of users, the friend could have sent one concurrently
+		if reverse, err := s.FriendRequestsRepo.GetPendingFriendRequestBetween(friendID, userID); err == nil {
+			return acceptReverse(reverse)
+		}
+		return ctx.Status(http.StatusConflict).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	s.pushFriendRequestEvent(friendID, r.ID, transport.InitFriendRequest)
+
+	return ctx.Status(http.StatusCreated).JSON(s.friendRequestDTOs(*r)[0])
+}
+
+// CancelFriendRequest withdraws the pending request sent from the user, the recipient is notified
+func (s UsersService) CancelFriendRequest(ctx *fiber.Ctx) error {
+	userID, _ := primitive.ObjectIDFromHex(ctx.Params("id"))
+	requestID, err := primitive.ObjectIDFromHex(ctx.Params("requestId"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid request id",
+		})
+	}
+
+	request, err := s.FriendRequestsRepo.CancelFriendRequest(requestID, userID)
 	if err != nil {
 		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	s.pushFriendRequestEvent(request.To, request.ID, transport.CancelFriendRequest)
+
+	return ctx.Status(http.StatusOK).JSON(s.friendRequestDTOs(*request)[0])
+}
+
+// FriendRequestDTO is a friend request with public profiles of its sender and recipient
+type FriendRequestDTO struct {
+	usersdb.FriendRequest `json:",inline"`
+	FromUser              *usersdb.PublicUser `json:"fromUser,omitempty"`
+	ToUser                *usersdb.PublicUser `json:"toUser,omitempty"`
+}
+
+// friendRequestDTOs attaches public profiles of users to the requests, profiles are omitted if they
+// can not be queried
+func (s UsersService) friendRequestDTOs(requests ...usersdb.FriendRequest) []FriendRequestDTO {
+	ids := make([]primitive.ObjectID, 0, len(requests)*2)
+	for _, r := range requests {
+		ids = append(ids, r.From, r.To)
+	}
+	users, err := s.UsersRepo.GetUsersByIDs(ids)
+	if err != nil {
+		log.Println("can not get users of friend requests:", err)
+	}
+	profiles := make(map[primitive.ObjectID]usersdb.PublicUser, len(users))
+	for _, u := range users {
+		profiles[u.ID] = u.Public()
+	}
+
+	dtos := make([]FriendRequestDTO, len(requests))
+	for i, r := range requests {
+		dtos[i].FriendRequest = r
+		if p, ok := profiles[r.From]; ok {
+			dtos[i].FromUser = &p
+		}
+		if p, ok := profiles[r.To]; ok {
+			dtos[i].ToUser = &p
+		}
+	}
+
+	return dtos
+}
+
+func (s UsersService) pushFriendRequestEvent(
+	userID primitive.ObjectID,
+	requestID primitive.ObjectID,
+	action transport.AddFriendAction,
+) {
 	event := transport.AddFriendEvent{
 		Event: transport.Event{Type: transport.AddFriend},
 		Payload: transport.AddFriendPayload{
-			UserID:             friendID.Hex(),
-			AddFriendRequestID: r.ID.Hex(),
-			Action:             transport.InitFriendRequest,
+			UserID:             userID.Hex(),
+			AddFriendRequestID: requestID.Hex(),
+			Action:             action,
 		},
 	}
 	notiPayload, _ := json.Marshal(event)
-	err = s.Transporter.Push(
+	err := s.Transporter.Push(
 		context.Background(),
 		s.ConsumerMap[transport.Notification],
 		notiPayload,
@@ -220,8 +587,6 @@ func (s UsersService) CreateAddFriendRequest(ctx *fiber.Ctx) error {
 	if err != nil {
 		log.Println("failed to push notification", err)
 	}
-
-	return ctx.Status(http.StatusCreated).JSON(r)
 }
 
 const (
@@ -249,50 +614,69 @@ func (s UsersService) RespondFriendRequest(ctx *fiber.Ctx) error {
 		})
 	}
 
-	var status usersdb.FriendRequestStatus
+	var (
+		request *usersdb.FriendRequest
+		action  transport.AddFriendAction
+	)
 	switch payload.Action {
 	case AcceptAddFriend:
-		status = usersdb.FriendStatusAccepted
+		request, err = s.acceptFriendRequest(requestID, userID)
+		action = transport.AcceptFriendRequest
 	case DenyAddFriend:
-		status = usersdb.FriendStatusDenied
+		request, err = s.FriendRequestsRepo.UpdateFriendRequestStatusByID(
+			requestID,
+			userID,
+			usersdb.FriendStatusDenied,
+		)
+		action = transport.DenyFriendRequest
 	default:
 		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
 			"error": "invalid action",
 		})
 	}
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	request, err := s.FriendRequestsRepo.UpdateFriendRequestStatusByID(
-		requestID,
-		userID,
-		status,
-	)
+	s.pushFriendRequestEvent(request.From, requestID, action)
+
+	return ctx.Status(http.StatusAccepted).JSON(s.friendRequestDTOs(*request)[0])
+}
+
+// RemoveFriend ends the friendship of the users, their individual conversation is kept as read only
+// and the removed friend is notified
+func (s UsersService) RemoveFriend(ctx *fiber.Ctx) error {
+	userID, _ := primitive.ObjectIDFromHex(ctx.Params("id"))
+	friendID, err := primitive.ObjectIDFromHex(ctx.Params("friendId"))
 	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid friend id",
+		})
+	}
+
+	err = s.UsersRepo.RemoveFriend(userID, friendID)
+	if err == usersdb.ErrNotFriends {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
 		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	var action transport.AddFriendAction
-	switch payload.Action {
-	case AcceptAddFriend:
-		// TODO: need to apply transaction
-		err = s.UsersRepo.AddFriend(request.From, request.To)
-		if err != nil {
-			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		action = transport.AcceptFriendRequest
-	case DenyAddFriend:
-		action = transport.DenyFriendRequest
+	err = s.ConversationsRepo.UpdateIndividualConversationReadOnly(userID, friendID, true)
+	if err != nil {
+		log.Println("failed to mark conversation as read only", err)
 	}
 
-	event := transport.AddFriendEvent{
-		Event: transport.Event{Type: transport.AddFriend},
-		Payload: transport.AddFriendPayload{
-			UserID:             request.From.Hex(),
-			AddFriendRequestID: requestID.Hex(),
-			Action:             action,
+	event := transport.RemoveFriendEvent{
+		Event: transport.Event{Type: transport.RemoveFriend},
+		Payload: transport.RemoveFriendPayload{
+			UserID:   friendID.Hex(),
+			FriendID: userID.Hex(),
 		},
 	}
 	notiPayload, _ := json.Marshal(event)
@@ -305,5 +689,103 @@ func (s UsersService) RespondFriendRequest(ctx *fiber.Ctx) error {
 		log.Println("failed to push notification", err)
 	}
 
-	return ctx.Status(http.StatusAccepted).JSON(request)
+	return ctx.SendStatus(http.StatusOK)
+}
+
+// BlockUser adds the user to the blocked list, blocked users are hidden from each other in user search
+func (s UsersService) BlockUser(ctx *fiber.Ctx) error {
+	userID, _ := primitive.ObjectIDFromHex(ctx.Params("id"))
+	blockedID, err := primitive.ObjectIDFromHex(ctx.Params("blockedId"))
+	if err != nil || blockedID == userID {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid blocked user id",
+		})
+	}
+
+	if _, err := s.UsersRepo.GetUserByID(blockedID); err == mongo.ErrNoDocuments {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{
+			"error": "user not found",
+		})
+	} else if err != nil {
+		log.Println("can not get blocked user:", err)
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "can not get user",
+		})
+	}
+
+	if err := s.UsersRepo.BlockUser(userID, blockedID); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.SendStatus(http.StatusOK)
+}
+
+func (s UsersService) UnblockUser(ctx *fiber.Ctx) error {
+	userID, _ := primitive.ObjectIDFromHex(ctx.Params("id"))
+	blockedID, err := primitive.ObjectIDFromHex(ctx.Params("blockedId"))
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": "invalid blocked user id",
+		})
+	}
+
+	if err := s.UsersRepo.UnblockUser(userID, blockedID); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.SendStatus(http.StatusOK)
+}
+
+// acceptFriendRequest accepts the request, makes friends and creates (or reopens) their individual conversation
+// in a transaction. The conversation joins the transaction only if users and chat databases share the client,
+// otherwise it is upserted after the transaction. If transactions are not supported (e.g. local single node),
+// the request is restored to pending when making friends fails.
+func (s UsersService) acceptFriendRequest(
+	requestID primitive.ObjectID,
+	userID primitive.ObjectID,
+) (*usersdb.FriendRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	client := s.UsersRepo.Database().Client()
+	conversationInTransaction := s.ConversationsRepo.Database().Client() == client
+
+	var request *usersdb.FriendRequest
+	err := dbutils.RunTransaction(ctx, client, func(ctx context.Context) error {
+		var err error
+		request, err = s.FriendRequestsRepo.UpdateFriendRequestStatusByIDWithContext(
+			ctx,
+			requestID,
+			userID,
+			usersdb.FriendStatusAccepted,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = s.UsersRepo.EnsureFriendsWithContext(ctx, request.From, request.To)
+		if err == nil && conversationInTransaction {
+			_, err = s.ConversationsRepo.UpsertIndividualConversationWithContext(ctx, request.From, request.To)
+		}
+		if err != nil && !dbutils.SupportsTransaction(ctx, client) {
+			_ = s.FriendRequestsRepo.RestorePendingFriendRequest(requestID)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !conversationInTransaction {
+		_, err = s.ConversationsRepo.UpsertIndividualConversationWithContext(ctx, request.From, request.To)
+		if err != nil {
+			log.Println("failed to create conversation of new friends", err)
+		}
+	}
+
+	return request, nil
 }
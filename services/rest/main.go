@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -11,8 +12,8 @@ import (
 	"blinders/packages/db/matchingdb"
 	"blinders/packages/db/usersdb"
 	dbutils "blinders/packages/db/utils"
+	"blinders/packages/storage"
 	"blinders/packages/transport"
-	"blinders/packages/utils"
 	restapi "blinders/services/rest/api"
 
 	"github.com/gofiber/fiber/v2"
@@ -43,24 +44,50 @@ func init() {
 	chatDB := chatdb.NewChatDB(db)
 	matchingRepo := matchingdb.NewMatchingRepo(db)
 
-	adminJSON, _ := utils.GetFile("firebase.admin.json")
-	auth, _ := auth.NewFirebaseManager(adminJSON)
+	authManager, err := auth.NewManagerFromEnv("firebase.admin.json")
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	transporter := transport.NewLocalTransport()
 	consumerMap := transport.ConsumerMap{
 		transport.Notification: "notification_service_id",
 		transport.Explore:      "explore_service_id",
+		// account jobs are run by `blinders account serve` in development
+		transport.Account: fmt.Sprintf("http://localhost:%s/", os.Getenv("ACCOUNT_SERVICE_PORT")),
+	}
+
+	// avatars are stored in a local directory and served by the api in development
+	storageDir := os.Getenv("LOCAL_STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = ".storage"
+	}
+	blobStorage, err := storage.NewLocalStorage(
+		storageDir,
+		fmt.Sprintf("http://localhost:%s/storage", os.Getenv("REST_API_PORT")),
+	)
+	if err != nil {
+		log.Fatal("failed to init local storage:", err)
+	}
+	// exports and feedback screenshots are stored out of the served directory, they are downloaded through the api
+	privateStorage, err := storage.NewPrivateStorageFromEnv(context.Background())
+	if err != nil {
+		log.Fatal("failed to init private storage:", err)
 	}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{BodyLimit: restapi.MaxRequestBodySize})
+	app.Static("/storage", storageDir)
 	apiManager = *restapi.NewManager(
 		app,
-		auth,
+		authManager,
 		usersDB,
 		chatDB,
 		matchingRepo,
+		blobStorage,
+		privateStorage,
 		transporter,
 		consumerMap,
+		auth.NewTicketStoreFromEnv(),
 	)
 
 	apiManager.App.Use(logger.New())
@@ -63,3 +63,13 @@ func ExplainPhraseInSentence(
 
 	return explainResult, nil
 }
+
+// BedrockExplainer explains phrases with the bedrock runtime,
+// it lets other services use the explanation behind an interface
+type BedrockExplainer struct {
+	Client *bedrockruntime.Client
+}
+
+func (e BedrockExplainer) Explain(phrase string, sentence string) (*collectingdb.ExplainResponse, error) {
+	return ExplainPhraseInSentence(e.Client, phrase, sentence)
+}
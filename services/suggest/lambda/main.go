@@ -50,11 +50,11 @@ func init() {
 			log.Fatalf("can not init database: %v", userDB)
 		}
 		usersRepo := usersdb.NewUsersRepo(userDB)
-		am, err := auth.NewFirebaseManagerFromFile("firebase.admin.json")
+		am, err := auth.NewManagerFromEnv("firebase.admin.json")
 		if err != nil {
 			log.Fatalf("can not create auth manager: %v", err)
 		}
-		authMiddleware = auth.LambdaAuthMiddleware(am, usersRepo)
+		authMiddleware = auth.LambdaAuthMiddleware(am, auth.NewCachedUsers(usersRepo, auth.NewUserCacheFromEnv()))
 	}()
 
 	wg.Add(1)